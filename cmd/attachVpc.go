@@ -0,0 +1,159 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// attachVpcCmd represents the attachVpc command
+var attachVpcCmd = &cobra.Command{
+	Use:   "attach-vpc",
+	Short: "Link a VPC to the reservation of its CIDR block",
+	Long: `Link a VPC created after its CIDR was reserved to the existing reservation.
+
+The VPC is described to check that the CIDR is one of its CIDR blocks, then the reservation
+is updated with the VPC ID, name and account, and its status moves to in-use.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		cidr, err := cmd.Flags().GetString("cidr")
+		vpcId, err := cmd.Flags().GetString("vpc-id")
+		account, err := cmd.Flags().GetString("account-id")
+		roleName, err := cmd.Flags().GetString("assume-role")
+		tagVpc, err := cmd.Flags().GetBool("tag-vpc")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		dynamoClient, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing EC2 client")
+		ec2Client, err := internalAws.GetEc2Client(cfg, serviceEndpoint("ec2"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if account != "" {
+			logger.Debug("Initializing STS client")
+			hubStsClient, err := internalAws.GetStsClient(cfg, serviceEndpoint("sts"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			assumedRoleArn, err := roleArnForAccount(account, roleName)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Debugf("Assuming role for account %s, role %s", account, assumedRoleArn)
+			assumeOpts, err := assumeRoleOptions()
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			assumedRoleCfg, err := internalAws.AssumeRole(cfg, hubStsClient, assumedRoleArn, assumeOpts)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			ec2Client, err = internalAws.GetEc2Client(assumedRoleCfg, serviceEndpoint("ec2"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+		}
+
+		logger.Debugf("Checking that VPC %s has CIDR %s", vpcId, cidr)
+		vpcInfo, err := internalAws.GetVpcForCIDR(ctx, ec2Client, vpcId, cidr)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if dryRun {
+			logger.Debugf("Planning attachment of VPC %s", vpcId)
+			change, err := internalAws.PlanAttachVpc(ctx, dynamoClient, tableName, vpcInfo)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			printPlan(logger, internalAws.Plan{
+				Command:   cmd.CommandPath(),
+				TableName: tableName,
+				Changes:   []internalAws.PlannedChange{change},
+			})
+
+			return
+		}
+
+		logger.Debugf("Attaching VPC %s to reservation %s", vpcId, vpcInfo.CIDR)
+		err = internalAws.AttachVpc(ctx, dynamoClient, tableName, vpcInfo)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("VPC %s attached to reservation %s", vpcId, vpcInfo.CIDR)
+
+		if tagVpc {
+			tagVpcWithReservation(ctx, logger, ec2Client, dynamoClient, tableName, vpcInfo.CIDR)
+		}
+	},
+}
+
+func init() {
+	// rootCmd.AddCommand(attachVpcCmd)
+	dynamodbCmd.AddCommand(attachVpcCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// attachVpcCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// attachVpcCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	attachVpcCmd.Flags().StringP("cidr", "c", "", "The reserved CIDR block to attach the VPC to")
+	attachVpcCmd.MarkFlagRequired("cidr")
+	attachVpcCmd.Flags().StringP("vpc-id", "v", "", "The ID of the VPC to attach")
+	attachVpcCmd.MarkFlagRequired("vpc-id")
+	attachVpcCmd.Flags().StringP("account-id", "a", "", "The AWS account ID the VPC was created in")
+	attachVpcCmd.Flags().String("assume-role", "", "The role name to assume (default is iam.assumedRoleName)")
+	attachVpcCmd.Flags().Bool("tag-vpc", false, "Tag the VPC with its reservation ID, pool and owner")
+	attachVpcCmd.Flags().Bool("dry-run", false, "Print the update plan without writing to DynamoDB")
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// backupExportCmd represents the backupExport command
+var backupExportCmd = &cobra.Command{
+	Use:   "export [file|s3://bucket/key]",
+	Short: "Dump every reservation to a JSON or NDJSON backup",
+	Long: `Dump every reservation to a JSON or NDJSON backup, including child and external reservations.
+
+The destination is a local file or an s3://bucket/key URI. Without a destination, or when it ends
+with a slash, the backup is named after the table and the time of the export. Files ending in
+.ndjson or .jsonl are written as NDJSON unless --format is set.
+
+A backup is a snapshot of the current reservations, not their history. Only the reservation
+fields are exported, other attributes of the items are dropped. VPCs imported by older versions
+store their ID as VpcID, which is not exported, so run attach-vpc for them first.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		format, err := cmd.Flags().GetString("format")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		destination := ""
+
+		if len(args) > 0 {
+			destination = args[0]
+		}
+
+		if format == "" {
+			format = internalAws.BackupFormatForPath(destination)
+		}
+
+		if destination == "" || strings.HasSuffix(destination, "/") {
+			destination += tableName + "-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		dynamoClient, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		actor, err := resolveActor(ctx, cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Exporting table %s", tableName)
+		backup, err := internalAws.ExportBackup(ctx, dynamoClient, tableName, actor)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		body, err := internalAws.MarshalBackup(backup, format)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if internalAws.IsS3URI(destination) {
+			logger.Debug("Initializing S3 client")
+			s3Client, err := internalAws.GetS3Client(cfg, serviceEndpoint("s3"), endpointOverridden("s3"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			versionID, err := internalAws.PutS3Object(ctx, s3Client, destination, body)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			if versionID != "" {
+				logger.Infof("S3 object version %s", versionID)
+			}
+		} else if err := os.WriteFile(destination, body, 0o600); err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("%d reservations exported to %s", backup.Count, destination)
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupExportCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// backupExportCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// backupExportCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	backupExportCmd.Flags().String("format", "", "The backup format, json or ndjson (default is taken from the file name, or json)")
+}
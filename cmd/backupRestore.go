@@ -0,0 +1,146 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// backupRestoreCmd represents the backupRestore command
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file|s3://bucket/key>",
+	Short: "Replay a backup into an empty table",
+	Long: `Replay a backup written by backup export into an empty table.
+
+Every reservation is validated first. Reservations with an invalid schema, duplicate CIDRs, or CIDRs
+that overlap a reservation that is restored before them are skipped and reported. Use --strict to
+restore nothing when any reservation would be skipped, and --dry-run to only validate the backup.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		format, err := cmd.Flags().GetString("format")
+		versionID, err := cmd.Flags().GetString("version-id")
+		strict, err := cmd.Flags().GetBool("strict")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		tableName := viper.GetString("dynamodb.tableName")
+		source := args[0]
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		if format == "" {
+			format = internalAws.BackupFormatForPath(source)
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		var body []byte
+
+		if internalAws.IsS3URI(source) {
+			logger.Debug("Initializing S3 client")
+			s3Client, err := internalAws.GetS3Client(cfg, serviceEndpoint("s3"), endpointOverridden("s3"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			body, err = internalAws.GetS3Object(ctx, s3Client, source, versionID)
+		} else {
+			body, err = internalAws.ReadBackupFile(source)
+		}
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		backup, err := internalAws.UnmarshalBackup(body, format)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("Backup of table %s exported at %s by %s", backup.TableName, backup.ExportedAt, backup.ExportedBy)
+		reservations, skipped := internalAws.PlanRestore(backup)
+
+		if len(skipped) > 0 {
+			logger.Warnf("%d of %d reservations will not be restored", len(skipped), backup.Count)
+
+			if err := internalAws.PrintSkippedReservations(skipped, output); err != nil {
+				logger.Fatal(err)
+			}
+
+			if strict {
+				logger.Fatal("Backup has invalid or overlapping reservations, nothing restored")
+			}
+		}
+
+		if dryRun {
+			logger.Infof("Dry run enabled, %d reservations would be restored to %s", len(reservations), tableName)
+			return
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		dynamoClient, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		empty, err := internalAws.TableIsEmpty(ctx, dynamoClient, tableName)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if !empty {
+			logger.Fatalf("Table %s is not empty, backups can only be restored into an empty table", tableName)
+		}
+
+		logger.Debugf("Restoring %d reservations", len(reservations))
+		restored, err := internalAws.ImportReservations(ctx, dynamoClient, tableName, reservations, logger)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("%d of %d reservations restored successfully", restored, backup.Count)
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupRestoreCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// backupRestoreCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// backupRestoreCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	backupRestoreCmd.Flags().String("format", "", "The backup format, json or ndjson (default is taken from the file name, or json)")
+	backupRestoreCmd.Flags().String("version-id", "", "Restore this version of a backup in a versioned S3 bucket")
+	backupRestoreCmd.Flags().Bool("strict", false, "Restore nothing when any reservation is invalid or overlaps")
+	backupRestoreCmd.Flags().Bool("dry-run", false, "Validate the backup without writing to DynamoDB")
+}
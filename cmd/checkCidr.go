@@ -0,0 +1,108 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkCidrCmd represents the checkCidr command
+var checkCidrCmd = &cobra.Command{
+	Use:   "check-cidr",
+	Short: "Check whether a CIDR block is free without reserving it",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		cidr, err := cmd.Flags().GetString("cidr")
+		pool, err := cmd.Flags().GetString("pool")
+		alternatives, err := cmd.Flags().GetInt("alternatives")
+		verifyTags, err := cmd.Flags().GetBool("verify-tags")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		pools, err := loadPools(pool)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		client, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Checking CIDR %s", cidr)
+		check, err := internalAws.CheckCIDR(ctx, client, tableName, cidr, pools, alternatives)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if verifyTags && len(check.Conflicts) > 0 {
+			logger.Debug("Initializing EC2 client")
+			ec2Client, err := internalAws.GetEc2Client(cfg, serviceEndpoint("ec2"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Debug("Verifying the reservation tags of the conflicting VPCs")
+			check.TagMismatches = internalAws.VerifyVpcTags(ctx, ec2Client, check.Conflicts, logger)
+		}
+
+		err = internalAws.PrintCIDRCheck(check, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if !check.Available {
+			logger.Fatalf("CIDR %s conflicts with existing reservations", check.CIDR)
+		}
+	},
+}
+
+func init() {
+	// rootCmd.AddCommand(checkCidrCmd)
+	dynamodbCmd.AddCommand(checkCidrCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// checkCidrCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// checkCidrCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	checkCidrCmd.Flags().StringP("cidr", "c", "", "The CIDR block to check")
+	checkCidrCmd.MarkFlagRequired("cidr")
+	checkCidrCmd.Flags().String("pool", "", "The pool CIDR to search for alternatives (default is the configured pool containing the CIDR)")
+	checkCidrCmd.Flags().Int("alternatives", 3, "The number of free alternative CIDRs of the same size to suggest")
+	checkCidrCmd.Flags().Bool("verify-tags", false, "Compare the reservation ID tag of each conflicting VPC with the reservation recorded for it")
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkRoutingCmd represents the checkRouting command
+var checkRoutingCmd = &cobra.Command{
+	Use:   "check-routing",
+	Short: "Find overlapping CIDRs between VPCs connected by Transit Gateway or peering",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		accounts, err := cmd.Flags().GetStringSlice("account-id")
+		roleName, err := cmd.Flags().GetString("assume-role")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		dynamoClient, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing EC2 client")
+		hubEC2Client, err := internalAws.GetEc2Client(cfg, serviceEndpoint("ec2"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing STS client")
+		hubStsClient, err := internalAws.GetStsClient(cfg, serviceEndpoint("sts"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		ec2Clients := []*ec2.Client{hubEC2Client}
+
+		for _, account := range accounts {
+			assumedRoleArn, err := roleArnForAccount(account, roleName)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Debugf("Assuming role for account %s, role %s", account, assumedRoleArn)
+			assumeOpts, err := assumeRoleOptions()
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			assumedRoleCfg, err := internalAws.AssumeRole(cfg, hubStsClient, assumedRoleArn, assumeOpts)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			spokeClient, err := internalAws.GetEc2Client(assumedRoleCfg, serviceEndpoint("ec2"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			ec2Clients = append(ec2Clients, spokeClient)
+		}
+
+		var discoveries []internalAws.RoutingDiscovery
+
+		for _, ec2Client := range ec2Clients {
+			logger.Debug("Discovering VPCs, Transit Gateway routes and peering connections")
+			discovery, err := internalAws.DiscoverRouting(ctx, ec2Client, logger)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Debugf("Found %d VPCs and %d routing paths", len(discovery.VpcCIDRs), len(discovery.Paths))
+			discoveries = append(discoveries, discovery)
+		}
+
+		conflicts, err := internalAws.CheckRouting(ctx, dynamoClient, tableName, discoveries)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if len(conflicts) == 0 {
+			logger.Info("No overlapping CIDRs found between connected VPCs")
+			return
+		}
+
+		err = internalAws.PrintRoutingConflicts(conflicts, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Fatalf("Found %d overlapping CIDRs between connected VPCs", len(conflicts))
+	},
+}
+
+func init() {
+	// rootCmd.AddCommand(checkRoutingCmd)
+	dynamodbCmd.AddCommand(checkRoutingCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// checkRoutingCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// checkRoutingCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	checkRoutingCmd.Flags().StringSliceP("account-id", "a", []string{}, "The spoke AWS accounts to discover VPCs and routes in, in addition to the current account")
+	checkRoutingCmd.Flags().String("assume-role", "", "The role name to assume in the spoke accounts (default is iam.assumedRoleName)")
+}
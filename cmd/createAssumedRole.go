@@ -5,12 +5,11 @@ package cmd
 
 import (
 	"context"
+	"time"
 
 	"github.com/asafdavid23/vpc-cidr-manager/internal/aws"
 	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
-	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -19,14 +18,24 @@ import (
 var createAssumedRoleCmd = &cobra.Command{
 	Use:   "assumed-role",
 	Short: "Create an assumed role for the VPC CIDR Manager",
+	Long: `Create an assumed role for the VPC CIDR Manager.
+
+By default the role is created as a stack in the current account. With --org the role is
+deployed as a service-managed StackSet to the organizational units given with --ou-id,
+optionally narrowed to the accounts given with --account-id, and the deployment status
+of every account is printed when it finishes. IAM roles are global, so the StackSet is
+deployed to a single region.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		logLevel, err := cmd.Flags().GetString("log-level")
-		roleName, err := cmd.Flags().GetString("role-name")
+		roleName := viper.GetString("iam.assumedRoleName")
 		logger := logging.NewLogger(logLevel)
-		hubAccount, err := cmd.Flags().GetString("hub-account")
-		assumeRolePrincipal := "arn:aws:iam::" + hubAccount + ":root"
+		hubAccount := viper.GetString("iam.hubAccountId")
 		ctx := context.TODO()
-		stackName := "vpc-cidr-manager-assumed-role"
+		iamTemplateFile, err := cmd.Flags().GetString("template")
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		stackName := roleStackName
+		org, err := cmd.Flags().GetBool("org")
+		outputFormat := viper.GetString("global.output")
 
 		region := viper.GetString("global.region")
 
@@ -34,20 +43,13 @@ var createAssumedRoleCmd = &cobra.Command{
 			logger.Fatal("region is not set")
 		}
 
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		cfg, err := loadAWSConfig(ctx, region)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		iamTemplateFile := "templates/cloudformation/iam_role.yml"
-
-		data := helpers.IAMTemplateData{
-			RoleName:  roleName,
-			Principal: assumeRolePrincipal,
-		}
-
-		renderedTemplate, err := helpers.LoadAndRenderIAMTemplate(iamTemplateFile, data)
+		renderedTemplate, err := renderRoleTemplate(iamTemplateFile, roleName, hubAccount)
 
 		if err != nil {
 			logger.Fatal(err)
@@ -56,26 +58,47 @@ var createAssumedRoleCmd = &cobra.Command{
 		logger.Debugf("Rendered template: %s", renderedTemplate)
 
 		logger.Debug("Iinitializing CloudFormation client")
-		cfnClient, err := aws.InitializeCFNClient(cfg)
+		cfnClient, err := aws.InitializeCFNClient(cfg, serviceEndpoint("cloudformation"))
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		logger.Debug("Creating CloudFormation stack")
+		if org {
+			stackSetOptions, err := stackSetOptionsFromFlags(cmd, region)
 
-		output, err := internalAws.CreateCFNStack(ctx, cfnClient, stackName, renderedTemplate)
+			if err != nil {
+				logger.Fatal(err)
+			}
 
-		if err != nil {
-			logger.Fatal(err)
+			logger.Debugf("Deploying StackSet %s", stackName)
+			err = internalAws.DeployCFNStackSet(ctx, cfnClient, stackName, renderedTemplate, stackSetOptions, timeout, logger)
+			statuses, statusErr := internalAws.GetStackInstanceStatuses(ctx, cfnClient, stackName, stackSetOptions)
+
+			if statusErr != nil {
+				logger.Error(statusErr)
+			} else if printErr := internalAws.PrintStackInstanceStatuses(statuses, outputFormat); printErr != nil {
+				logger.Error(printErr)
+			}
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("StackSet %s deployed successfully", stackName)
+			return
 		}
 
-		err = internalAws.WaitForStackToBeCreated(ctx, cfnClient, stackName)
+		logger.Debug("Creating CloudFormation stack")
+
+		since := time.Now()
+		output, err := internalAws.CreateCFNStack(ctx, cfnClient, stackName, renderedTemplate)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
+		waitForStack(ctx, logger, cfnClient, *output.StackId, since, timeout)
 		logger.Infof("Stack %s created successfully", *output.StackId)
 	},
 }
@@ -94,7 +117,16 @@ func init() {
 	// is called directly, e.g.:
 	// createAssumedRuleCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	createAssumedRoleCmd.Flags().StringP("role-name", "r", "", "The name of the role to create")
+	createAssumedRoleCmd.Flags().String("template", "", "A custom CloudFormation template to use instead of the built-in one")
+	createAssumedRoleCmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for the stack operation to finish")
 	createAssumedRoleCmd.Flags().String("hub-account", "", "The principal that assume the role in spoke accoount")
+	createAssumedRoleCmd.Flags().Bool("org", false, "Deploy the role to the organization as a service-managed StackSet")
+	createAssumedRoleCmd.Flags().StringSlice("ou-id", []string{}, "The organizational units to deploy the StackSet to")
+	createAssumedRoleCmd.Flags().StringSlice("account-id", []string{}, "Only deploy the StackSet to these accounts within the organizational units")
+	createAssumedRoleCmd.Flags().StringSlice("regions", []string{}, "The region to deploy the StackSet instances to, only one since the role is global (default is global.region)")
+	createAssumedRoleCmd.Flags().Bool("auto-deploy", true, "Deploy the role to accounts added to the organizational units later")
+	createAssumedRoleCmd.Flags().Bool("retain-on-removal", false, "Keep the role in accounts removed from the organizational units")
+	createAssumedRoleCmd.Flags().Bool("delegated-admin", false, "Manage the StackSet as a delegated administrator instead of from the management account")
 
 	viper.BindPFlag("iam.hubAccountId", createAssumedRoleCmd.Flags().Lookup("hub-account"))
 	viper.BindPFlag("iam.assumedRoleName", createAssumedRoleCmd.Flags().Lookup("role-name"))
@@ -5,11 +5,11 @@ package cmd
 
 import (
 	"context"
+	"time"
 
 	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -18,33 +18,101 @@ import (
 var createDynamoDBTableCmd = &cobra.Command{
 	Use:   "dynamodb-table",
 	Short: "Create the VpcCidrReservations table in DynamoDB",
+	Long: `Create the VpcCidrReservations table in DynamoDB with a CloudFormation stack.
+
+With --generate-iaac-template nothing is created. Instead the table and the spoke IAM role are
+written as Terraform HCL and CloudFormation YAML to --template-dir, for teams that manage their
+infrastructure themselves. The role is rendered from iam.assumedRoleName and iam.hubAccountId.
+
+Billing, capacity, point-in-time recovery, KMS encryption, deletion protection, tags and replica
+regions are read from the dynamodb section of the config, or from the flags below, and apply to
+the stack, the generated templates and --sdk alike. With replica regions the table is created as
+a global table.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		logLevel, err := cmd.Flags().GetString("log-level")
 		tableName := viper.GetString("dynamodb.tableName")
 		logger := logging.NewLogger(logLevel)
 		ctx := context.TODO()
-		stackName := "vpc-cidr-manager-dynamodb-table"
+		dynamodbTableTemplateFile, err := cmd.Flags().GetString("template")
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		stackName := tableStackName
 		dryRun, err := cmd.Flags().GetBool("dry-run")
-		// generateTemplate, err := cmd.Flags().GetBool("generate-iaac-template")
+		generateTemplate, err := cmd.Flags().GetBool("generate-iaac-template")
+		templateDir, err := cmd.Flags().GetString("template-dir")
+		force, err := cmd.Flags().GetBool("force")
+		useSDK, err := cmd.Flags().GetBool("sdk")
+
+		// Tags given on the command line replace the tags from the config
+		if cmd.Flags().Changed("tag") {
+			tags, err := cmd.Flags().GetStringToString("tag")
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			viper.Set("dynamodb.tags", tags)
+		}
+
+		if generateTemplate {
+			paths, err := generateIaacTemplates(templateDir, dynamodbTableTemplateFile, tableName, viper.GetString("iam.assumedRoleName"), viper.GetString("iam.hubAccountId"), force)
+
+			for _, path := range paths {
+				logger.Infof("Template written to %s", path)
+			}
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			return
+		}
+
 		region := viper.GetString("global.region")
 
 		if region == "" {
 			logger.Fatal("region is not set")
 		}
 
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		cfg, err := loadAWSConfig(ctx, region)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		dynamodbTableTemplateFile := "templates/cloudformation/dynamodb_table.yml"
+		if useSDK {
+			opts, err := tableOptions()
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			dynamoClient, err := internalAws.GetDynamoDBClient(cfg, serviceEndpoint("dynamodb"))
 
-		data := helpers.DynamoDBTableTemplateData{
-			TableName: tableName,
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			if dryRun {
+				logger.Infof("Dry run enabled, not creating table %s (%s)", tableName, opts.BillingMode)
+				return
+			}
+
+			created, err := internalAws.CreateDynamoDBTable(ctx, dynamoClient, tableName, opts, logger)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			if !created {
+				logger.Warnf("Table %s already exists, the table options were ignored", tableName)
+				return
+			}
+
+			logger.Infof("Table %s created successfully", tableName)
+			return
 		}
 
-		renderedTemplate, err := helpers.LoadAndRenderCFNTemplate(dynamodbTableTemplateFile, data)
+		renderedTemplate, err := renderTableTemplate(dynamodbTableTemplateFile, tableName)
 
 		if err != nil {
 			logger.Fatal(err)
@@ -53,7 +121,7 @@ var createDynamoDBTableCmd = &cobra.Command{
 		logger.Debugf("Rendered template: %s", renderedTemplate)
 
 		logger.Debug("Iinitializing CloudFormation client")
-		cfnClient, err := internalAws.InitializeCFNClient(cfg)
+		cfnClient, err := internalAws.InitializeCFNClient(cfg, serviceEndpoint("cloudformation"))
 
 		if err != nil {
 			logger.Fatal(err)
@@ -70,18 +138,14 @@ var createDynamoDBTableCmd = &cobra.Command{
 		} else {
 			logger.Debug("Creating cloudformation stack")
 
+			since := time.Now()
 			output, err := internalAws.CreateCFNStack(ctx, cfnClient, stackName, renderedTemplate)
 
 			if err != nil {
 				logger.Fatal(err)
 			}
 
-			err = internalAws.WaitForStackToBeCreated(ctx, cfnClient, stackName)
-
-			if err != nil {
-				logger.Fatal(err)
-			}
-
+			waitForStack(ctx, logger, cfnClient, *output.StackId, since, timeout)
 			logger.Infof("Stack %s created successfully", *output.StackId)
 		}
 	},
@@ -101,8 +165,29 @@ func init() {
 	// is called directly, e.g.:
 	// createTableCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	createDynamoDBTableCmd.Flags().StringP("name", "n", "", "The name of the table to create")
+	createDynamoDBTableCmd.Flags().String("template", "", "A custom CloudFormation template to use instead of the built-in one")
+	createDynamoDBTableCmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for the stack operation to finish")
 	createDynamoDBTableCmd.Flags().Bool("dry-run", false, "Print the rendered CloudFormation template without creating the stack")
-	createDynamoDBTableCmd.Flags().Bool("generate-iaac-template", false, "Generate the CloudFormation template without creating the stack")
+	createDynamoDBTableCmd.Flags().Bool("generate-iaac-template", false, "Write Terraform and CloudFormation templates for the table and spoke role without creating the stack")
+	createDynamoDBTableCmd.Flags().String("template-dir", "iaac", "The directory --generate-iaac-template writes the templates to")
+	createDynamoDBTableCmd.Flags().Bool("force", false, "Overwrite generated templates that already exist")
+
+	createDynamoDBTableCmd.Flags().Bool("sdk", false, "Create the table directly with the DynamoDB API instead of a CloudFormation stack")
+	createDynamoDBTableCmd.Flags().String("billing-mode", helpers.BillingModeProvisioned, "The billing mode of the table, PROVISIONED or PAY_PER_REQUEST (on-demand)")
+	createDynamoDBTableCmd.Flags().Int64("read-capacity", 1, "The provisioned read capacity units")
+	createDynamoDBTableCmd.Flags().Int64("write-capacity", 1, "The provisioned write capacity units")
+	createDynamoDBTableCmd.Flags().Bool("pitr", false, "Enable point-in-time recovery")
+	createDynamoDBTableCmd.Flags().Bool("deletion-protection", false, "Enable deletion protection")
+	createDynamoDBTableCmd.Flags().String("kms-key-arn", "", "Encrypt the table with this customer managed KMS key")
+	createDynamoDBTableCmd.Flags().StringSlice("replica-regions", []string{}, "Deploy the table as a global table replicated to these regions")
+	createDynamoDBTableCmd.Flags().StringToString("tag", map[string]string{}, "Tags to add to the table, as key=value")
 
 	viper.BindPFlag("dynamodb.tableName", createDynamoDBTableCmd.Flags().Lookup("name"))
+	viper.BindPFlag("dynamodb.billingMode", createDynamoDBTableCmd.Flags().Lookup("billing-mode"))
+	viper.BindPFlag("dynamodb.readCapacity", createDynamoDBTableCmd.Flags().Lookup("read-capacity"))
+	viper.BindPFlag("dynamodb.writeCapacity", createDynamoDBTableCmd.Flags().Lookup("write-capacity"))
+	viper.BindPFlag("dynamodb.pointInTimeRecovery", createDynamoDBTableCmd.Flags().Lookup("pitr"))
+	viper.BindPFlag("dynamodb.deletionProtection", createDynamoDBTableCmd.Flags().Lookup("deletion-protection"))
+	viper.BindPFlag("dynamodb.kmsKeyArn", createDynamoDBTableCmd.Flags().Lookup("kms-key-arn"))
+	viper.BindPFlag("dynamodb.replicaRegions", createDynamoDBTableCmd.Flags().Lookup("replica-regions"))
 }
@@ -0,0 +1,82 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// freeCidrsCmd represents the freeCidrs command
+var freeCidrsCmd = &cobra.Command{
+	Use:   "free-cidrs",
+	Short: "List the free CIDR blocks inside a supernet",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		within, err := cmd.Flags().GetString("within")
+		prefixSize, err := cmd.Flags().GetInt("prefix-size")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		client, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Listing free CIDRs within %s", within)
+		freeCIDRs, err := internalAws.ListFreeCIDRs(ctx, client, tableName, within, prefixSize)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		err = internalAws.PrintFreeCIDRs(freeCIDRs, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	// rootCmd.AddCommand(freeCidrsCmd)
+	dynamodbCmd.AddCommand(freeCidrsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// freeCidrsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// freeCidrsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	freeCidrsCmd.Flags().String("within", "", "The supernet to list free CIDR blocks in")
+	freeCidrsCmd.MarkFlagRequired("within")
+	freeCidrsCmd.Flags().Int("prefix-size", 0, "Only list free blocks large enough to hold a CIDR of this prefix size")
+}
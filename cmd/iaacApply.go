@@ -0,0 +1,159 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// iaacApplyCmd represents the iaacApply command
+var iaacApplyCmd = &cobra.Command{
+	Use:   "apply <dynamodb-table|assumed-role>",
+	Short: "Create or update a stack through a CloudFormation change set",
+	Long: `Create or update a stack through a CloudFormation change set.
+
+The change set is printed for review before it is executed. Use --dry-run to only print it,
+or --yes to execute it without asking. The hub policy depends on the enabled features
+and is deployed with iaac policy --deploy instead.`,
+	ValidArgs: []string{"dynamodb-table", "assumed-role"},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+
+		switch args[0] {
+		case "dynamodb-table", "assumed-role":
+			return nil
+		case "hub-policy":
+			return fmt.Errorf("the hub policy depends on the enabled features, deploy it with iaac policy --deploy")
+		default:
+			return fmt.Errorf("unknown stack %s, expected dynamodb-table or assumed-role", args[0])
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		templateFile, err := cmd.Flags().GetString("template")
+		roleName, err := cmd.Flags().GetString("role-name")
+		hubAccount, err := cmd.Flags().GetString("hub-account")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		yes, err := cmd.Flags().GetBool("yes")
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("region is not set")
+		}
+
+		stackName, err := iaacStackName(args[0])
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		var renderedTemplate string
+
+		switch stackName {
+		case tableStackName:
+			renderedTemplate, err = renderTableTemplate(templateFile, viper.GetString("dynamodb.tableName"))
+		case roleStackName:
+			if roleName == "" {
+				roleName = viper.GetString("iam.assumedRoleName")
+			}
+
+			if hubAccount == "" {
+				hubAccount = viper.GetString("iam.hubAccountId")
+			}
+
+			renderedTemplate, err = renderRoleTemplate(templateFile, roleName, hubAccount)
+		}
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Rendered template: %s", renderedTemplate)
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing CloudFormation client")
+		cfnClient, err := internalAws.InitializeCFNClient(cfg, serviceEndpoint("cloudformation"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Creating change set for stack %s", stackName)
+		changeSet, err := internalAws.CreateCFNChangeSet(ctx, cfnClient, stackName, renderedTemplate)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if len(changeSet.Changes) == 0 {
+			logger.Infof("Stack %s is up to date", stackName)
+			return
+		}
+
+		err = internalAws.PrintChangeSet(changeSet, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if dryRun || (!yes && !confirm("Execute change set "+changeSet.Name+"?")) {
+			logger.Infof("Change set %s not executed, deleting it", changeSet.Name)
+
+			if err := internalAws.DeleteCFNChangeSet(ctx, cfnClient, changeSet); err != nil {
+				logger.Fatal(err)
+			}
+
+			return
+		}
+
+		logger.Debugf("Executing change set %s", changeSet.Name)
+		since := time.Now()
+		err = internalAws.ExecuteCFNChangeSet(ctx, cfnClient, changeSet)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		waitForStack(ctx, logger, cfnClient, stackName, since, timeout)
+		logger.Infof("Stack %s applied successfully", stackName)
+	},
+}
+
+func init() {
+	iaacCmd.AddCommand(iaacApplyCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// iaacApplyCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// iaacApplyCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	iaacApplyCmd.Flags().String("template", "", "A custom CloudFormation template to use instead of the built-in one")
+	iaacApplyCmd.Flags().StringP("role-name", "r", "", "The name of the spoke role (default is iam.assumedRoleName)")
+	iaacApplyCmd.Flags().String("hub-account", "", "The hub account trusted by the spoke role (default is iam.hubAccountId)")
+	iaacApplyCmd.Flags().Bool("dry-run", false, "Print the change set without executing it")
+	iaacApplyCmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for the stack operation to finish")
+	iaacApplyCmd.Flags().BoolP("yes", "y", false, "Execute the change set without asking for confirmation")
+}
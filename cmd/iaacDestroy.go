@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"time"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// iaacDestroyCmd represents the iaacDestroy command
+var iaacDestroyCmd = &cobra.Command{
+	Use:   "destroy <dynamodb-table|assumed-role|hub-policy>",
+	Short: "Delete a stack created by the iaac commands",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		yes, err := cmd.Flags().GetBool("yes")
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("region is not set")
+		}
+
+		stackName, err := iaacStackName(args[0])
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing CloudFormation client")
+		cfnClient, err := internalAws.InitializeCFNClient(cfg, serviceEndpoint("cloudformation"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		stack, err := internalAws.GetCFNStack(ctx, cfnClient, stackName)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if stack == nil {
+			logger.Infof("Stack %s does not exist", stackName)
+			return
+		}
+
+		if !yes && !confirm("Delete stack "+stackName+" and all of its resources?") {
+			logger.Info("Stack not deleted")
+			return
+		}
+
+		logger.Debugf("Deleting stack %s", stackName)
+		since := time.Now()
+		err = internalAws.DeleteCFNStack(ctx, cfnClient, stackName)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		waitForStack(ctx, logger, cfnClient, *stack.StackId, since, timeout)
+		logger.Infof("Stack %s deleted successfully", stackName)
+	},
+}
+
+func init() {
+	iaacCmd.AddCommand(iaacDestroyCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// iaacDestroyCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// iaacDestroyCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	iaacDestroyCmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for the stack operation to finish")
+	iaacDestroyCmd.Flags().BoolP("yes", "y", false, "Delete the stack without asking for confirmation")
+}
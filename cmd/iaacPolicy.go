@@ -0,0 +1,222 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/asafdavid23/vpc-cidr-manager/templates"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// iaacPolicyCmd represents the iaacPolicy command
+var iaacPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Print the least-privilege IAM policy for the identity running the CLI",
+	Long: `Print the least-privilege IAM policy for the identity running the CLI in the hub account.
+
+The policy only grants what the features given with --features need, scoped to the configured
+table, to the spoke roles of the accounts given with --account-id, and to the backups under
+--backup-location. Features: ` + strings.Join(helpers.HubPolicyFeatures, ", ") + `.
+
+With --deploy the policy is created or updated as a managed policy in the ` + hubPolicyStackName + `
+stack, through a change set like iaac apply.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		features, err := cmd.Flags().GetStringSlice("features")
+		accountIDs, err := cmd.Flags().GetStringSlice("account-id")
+		hubAccount, err := cmd.Flags().GetString("hub-account")
+		deploy, err := cmd.Flags().GetBool("deploy")
+		policyName, err := cmd.Flags().GetString("policy-name")
+		yes, err := cmd.Flags().GetBool("yes")
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		backupLocation, err := cmd.Flags().GetString("backup-location")
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("region is not set")
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if hubAccount == "" {
+			hubAccount = viper.GetString("iam.hubAccountId")
+		}
+
+		if hubAccount == "" {
+			logger.Debug("Hub account is not set, resolving it from the caller identity")
+			stsClient, err := internalAws.GetStsClient(cfg, serviceEndpoint("sts"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			identity, err := internalAws.ResolveIdentity(ctx, stsClient)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			hubAccount = identity.AccountID
+		}
+
+		// Without spoke accounts the role can be assumed in any account of the partition
+		if len(accountIDs) == 0 {
+			accountIDs = []string{"*"}
+		}
+
+		tableRegion, err := homeRegion()
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		var backupBucket, backupPrefix string
+
+		if backupLocation != "" {
+			backupBucket, backupPrefix, err = internalAws.ParseS3URI(backupLocation)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+		}
+
+		var roleArns []string
+
+		for _, accountID := range accountIDs {
+			roleArn, err := roleArnForAccount(accountID, "")
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			roleArns = append(roleArns, roleArn)
+		}
+
+		policy, err := helpers.HubPolicy(features, helpers.HubPolicyData{
+			Partition:      viper.GetString("iam.partition"),
+			Region:         tableRegion,
+			AccountID:      hubAccount,
+			TableName:      viper.GetString("dynamodb.tableName"),
+			ReplicaRegions: append([]string{region}, viper.GetStringSlice("dynamodb.replicaRegions")...),
+			RoleArns:       roleArns,
+			StackNames:     []string{tableStackName, roleStackName, hubPolicyStackName},
+			StackSetName:   roleStackName,
+			KMSKeyArn:      viper.GetString("dynamodb.kmsKeyArn"),
+			BackupBucket:   backupBucket,
+			BackupPrefix:   backupPrefix,
+		})
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if !deploy {
+			if err := internalAws.PrintPolicyDocument(policy, output); err != nil {
+				logger.Fatal(err)
+			}
+
+			return
+		}
+
+		policyDocument, err := json.Marshal(policy)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		renderedTemplate, err := helpers.RenderBuiltinTemplate(templates.HubPolicy, helpers.HubPolicyTemplateData{
+			PolicyName:     policyName,
+			PolicyDocument: string(policyDocument),
+		})
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Rendered template: %s", renderedTemplate)
+
+		logger.Debug("Initializing CloudFormation client")
+		cfnClient, err := internalAws.InitializeCFNClient(cfg, serviceEndpoint("cloudformation"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Creating change set for stack %s", hubPolicyStackName)
+		changeSet, err := internalAws.CreateCFNChangeSet(ctx, cfnClient, hubPolicyStackName, renderedTemplate)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if len(changeSet.Changes) == 0 {
+			logger.Infof("Stack %s is up to date", hubPolicyStackName)
+			return
+		}
+
+		err = internalAws.PrintChangeSet(changeSet, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if !yes && !confirm("Execute change set "+changeSet.Name+"?") {
+			logger.Infof("Change set %s not executed, deleting it", changeSet.Name)
+
+			if err := internalAws.DeleteCFNChangeSet(ctx, cfnClient, changeSet); err != nil {
+				logger.Fatal(err)
+			}
+
+			return
+		}
+
+		logger.Debugf("Executing change set %s", changeSet.Name)
+		since := time.Now()
+		err = internalAws.ExecuteCFNChangeSet(ctx, cfnClient, changeSet)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		waitForStack(ctx, logger, cfnClient, hubPolicyStackName, since, timeout)
+		logger.Infof("Policy %s deployed successfully", policyName)
+	},
+}
+
+func init() {
+	iaacCmd.AddCommand(iaacPolicyCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// iaacPolicyCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// iaacPolicyCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	iaacPolicyCmd.Flags().StringSlice("features", []string{helpers.FeatureReservations, helpers.FeatureCrossAccount, helpers.FeatureImport}, "The features to grant permissions for")
+	iaacPolicyCmd.Flags().StringSlice("account-id", []string{}, "The spoke accounts whose role may be assumed (default is any account)")
+	iaacPolicyCmd.Flags().String("hub-account", "", "The account the table and stacks live in (default is iam.hubAccountId or the caller's account)")
+	iaacPolicyCmd.Flags().String("backup-location", "", "The s3://bucket/prefix backups are exported to, required by the backup feature")
+	iaacPolicyCmd.Flags().Bool("deploy", false, "Create or update the policy as a managed policy stack")
+	iaacPolicyCmd.Flags().String("policy-name", "vpc-cidr-manager-hub", "The name of the managed policy created with --deploy")
+	iaacPolicyCmd.Flags().Duration("timeout", 30*time.Minute, "How long to wait for the stack operation to finish")
+	iaacPolicyCmd.Flags().BoolP("yes", "y", false, "Execute the change set without asking for confirmation")
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// iaacStatusCmd represents the iaacStatus command
+var iaacStatusCmd = &cobra.Command{
+	Use:   "status [dynamodb-table|assumed-role|hub-policy...]",
+	Short: "Show the status of the stacks created by the iaac commands",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		region := viper.GetString("global.region")
+		org, err := cmd.Flags().GetBool("org")
+		delegatedAdmin, err := cmd.Flags().GetBool("delegated-admin")
+
+		if region == "" {
+			logger.Fatal("region is not set")
+		}
+
+		stackNames := []string{tableStackName, roleStackName, hubPolicyStackName}
+
+		if len(args) > 0 {
+			stackNames = nil
+
+			for _, arg := range args {
+				stackName, err := iaacStackName(arg)
+
+				if err != nil {
+					logger.Fatal(err)
+				}
+
+				stackNames = append(stackNames, stackName)
+			}
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing CloudFormation client")
+		cfnClient, err := internalAws.InitializeCFNClient(cfg, serviceEndpoint("cloudformation"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if org {
+			statuses, err := internalAws.GetStackInstanceStatuses(ctx, cfnClient, roleStackName, internalAws.StackSetOptions{DelegatedAdmin: delegatedAdmin})
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			err = internalAws.PrintStackInstanceStatuses(statuses, output)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			return
+		}
+
+		statuses, err := internalAws.GetStackStatuses(ctx, cfnClient, stackNames)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		err = internalAws.PrintStackStatuses(statuses, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	iaacCmd.AddCommand(iaacStatusCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// iaacStatusCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// iaacStatusCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	iaacStatusCmd.Flags().Bool("org", false, "Show the per-account status of the assumed-role StackSet")
+	iaacStatusCmd.Flags().Bool("delegated-admin", false, "Read the StackSet as a delegated administrator")
+}
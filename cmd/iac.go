@@ -4,9 +4,36 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/asafdavid23/vpc-cidr-manager/templates"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Names of the CloudFormation stacks managed by the iaac commands.
+const (
+	tableStackName     = "vpc-cidr-manager-dynamodb-table"
+	roleStackName      = "vpc-cidr-manager-assumed-role"
+	hubPolicyStackName = "vpc-cidr-manager-hub-policy"
 )
 
+// iaacStacks maps the stack arguments of the iaac commands to their stack names.
+var iaacStacks = map[string]string{
+	"dynamodb-table": tableStackName,
+	"assumed-role":   roleStackName,
+	"hub-policy":     hubPolicyStackName,
+}
+
 // iamCmd represents the iam command
 var iaacCmd = &cobra.Command{
 	Use:   "iaac",
@@ -28,3 +55,260 @@ func init() {
 	// is called directly, e.g.:
 	// iamCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// iaacStackName returns the stack name for a stack argument of the iaac commands.
+func iaacStackName(stack string) (string, error) {
+	stackName, ok := iaacStacks[stack]
+
+	if !ok {
+		return "", fmt.Errorf("unknown stack %s, expected dynamodb-table, assumed-role or hub-policy", stack)
+	}
+
+	return stackName, nil
+}
+
+// tableOptions returns the table settings from the dynamodb section of the config.
+func tableOptions() (helpers.TableOptions, error) {
+	return helpers.TableOptions{
+		BillingMode:         viper.GetString("dynamodb.billingMode"),
+		ReadCapacity:        viper.GetInt64("dynamodb.readCapacity"),
+		WriteCapacity:       viper.GetInt64("dynamodb.writeCapacity"),
+		PointInTimeRecovery: viper.GetBool("dynamodb.pointInTimeRecovery"),
+		DeletionProtection:  viper.GetBool("dynamodb.deletionProtection"),
+		KMSKeyArn:           viper.GetString("dynamodb.kmsKeyArn"),
+		Tags:                viper.GetStringMapString("dynamodb.tags"),
+		Region:              viper.GetString("global.region"),
+		ReplicaRegions:      viper.GetStringSlice("dynamodb.replicaRegions"),
+	}.Normalize()
+}
+
+// tableTemplateData returns the values the DynamoDB table templates are rendered with.
+func tableTemplateData(tableName string) (helpers.DynamoDBTableTemplateData, error) {
+	if tableName == "" {
+		return helpers.DynamoDBTableTemplateData{}, fmt.Errorf("table name is not set")
+	}
+
+	opts, err := tableOptions()
+
+	if err != nil {
+		return helpers.DynamoDBTableTemplateData{}, err
+	}
+
+	return helpers.DynamoDBTableTemplateData{
+		TableName:    tableName,
+		TableOptions: opts,
+	}, nil
+}
+
+// renderTableTemplate renders the DynamoDB table template, the built-in one unless templateFile is set.
+func renderTableTemplate(templateFile string, tableName string) (string, error) {
+	data, err := tableTemplateData(tableName)
+
+	if err != nil {
+		return "", err
+	}
+
+	return helpers.LoadAndRenderCFNTemplate(templateFile, data)
+}
+
+// roleTemplateData returns the values the spoke role templates are rendered with. The role trusts
+// the hub account and requires the external ID and MFA configured under iam.
+func roleTemplateData(roleName string, hubAccount string) (helpers.IAMTemplateData, error) {
+	if roleName == "" || hubAccount == "" {
+		return helpers.IAMTemplateData{}, fmt.Errorf("role name and hub account are required")
+	}
+
+	data := helpers.IAMTemplateData{
+		RoleName:   roleName,
+		RolePath:   helpers.NormalizeRolePath(viper.GetString("iam.rolePath")),
+		Principal:  "arn:" + viper.GetString("iam.partition") + ":iam::" + hubAccount + ":root",
+		ExternalID: viper.GetString("iam.externalId"),
+		RequireMFA: viper.GetString("iam.mfaSerial") != "",
+	}
+
+	// Roles allow one hour sessions by default, only longer sessions need the limit raised
+	if sessionDuration := viper.GetDuration("iam.sessionDuration"); sessionDuration > time.Hour {
+		data.MaxSessionDuration = int(sessionDuration.Seconds())
+	}
+
+	return data, nil
+}
+
+// renderRoleTemplate renders the spoke role template, the built-in one unless templateFile is set.
+func renderRoleTemplate(templateFile string, roleName string, hubAccount string) (string, error) {
+	data, err := roleTemplateData(roleName, hubAccount)
+
+	if err != nil {
+		return "", err
+	}
+
+	return helpers.LoadAndRenderIAMTemplate(templateFile, data)
+}
+
+// generateIaacTemplates renders the table and spoke role as Terraform HCL and CloudFormation YAML into dir,
+// laid out like the built-in templates, and returns the paths it wrote. Existing files are only
+// overwritten with force.
+func generateIaacTemplates(dir string, tableTemplateFile string, tableName string, roleName string, hubAccount string, force bool) ([]string, error) {
+	tableData, err := tableTemplateData(tableName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	roleData, err := roleTemplateData(roleName, hubAccount)
+
+	if err != nil {
+		return nil, fmt.Errorf("%v, set iam.assumedRoleName and iam.hubAccountId to generate the spoke role", err)
+	}
+
+	rendered := map[string]func() (string, error){
+		templates.TerraformDynamoDBTable: func() (string, error) {
+			return helpers.RenderBuiltinTemplate(templates.TerraformDynamoDBTable, tableData)
+		},
+		templates.TerraformIAMRole: func() (string, error) {
+			return helpers.RenderBuiltinTemplate(templates.TerraformIAMRole, roleData)
+		},
+		templates.DynamoDBTable: func() (string, error) {
+			return helpers.LoadAndRenderCFNTemplate(tableTemplateFile, tableData)
+		},
+		templates.IAMRole: func() (string, error) {
+			return helpers.LoadAndRenderIAMTemplate("", roleData)
+		},
+	}
+
+	// Render and check every file before writing any, so a failure never leaves a partial set behind.
+	names := []string{templates.TerraformDynamoDBTable, templates.TerraformIAMRole, templates.DynamoDBTable, templates.IAMRole}
+	contents := make(map[string]string, len(names))
+	var existing []string
+
+	for _, name := range names {
+		content, err := rendered[name]()
+
+		if err != nil {
+			return nil, err
+		}
+
+		contents[name] = content
+		path := filepath.Join(dir, name)
+
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+
+	if len(existing) > 0 && !force {
+		return nil, fmt.Errorf("%s already exists, use --force to overwrite", strings.Join(existing, ", "))
+	}
+
+	var paths []string
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return paths, err
+		}
+
+		if err := os.WriteFile(path, []byte(contents[name]), 0o644); err != nil {
+			return paths, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// waitForStack streams the stack events until the stack operation finishes. When it fails or times out,
+// the user is offered to roll back or delete the stack before the command exits non-zero.
+func waitForStack(ctx context.Context, logger *log.Logger, cfnClient *cloudformation.Client, stackName string, since time.Time, timeout time.Duration) {
+	err := internalAws.WaitForStack(ctx, cfnClient, stackName, since, timeout, logger)
+
+	if err == nil {
+		return
+	}
+
+	logger.Error(err)
+
+	action, recoverStack, recoverErr := internalAws.RecoverCFNStack(ctx, cfnClient, stackName)
+
+	if recoverErr != nil {
+		logger.Fatal(recoverErr)
+	}
+
+	if action != "" && confirm("Do you want to "+action+" "+stackName+"?") {
+		if err := recoverStack(); err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("Started to %s %s", action, stackName)
+	}
+
+	logger.Fatalf("Stack %s did not complete", stackName)
+}
+
+// stackSetOptionsFromFlags reads the organization deployment flags of a command. The regions default to
+// the region the command runs in.
+func stackSetOptionsFromFlags(cmd *cobra.Command, region string) (internalAws.StackSetOptions, error) {
+	ouIDs, err := cmd.Flags().GetStringSlice("ou-id")
+
+	if err != nil {
+		return internalAws.StackSetOptions{}, err
+	}
+
+	accountIDs, err := cmd.Flags().GetStringSlice("account-id")
+
+	if err != nil {
+		return internalAws.StackSetOptions{}, err
+	}
+
+	regions, err := cmd.Flags().GetStringSlice("regions")
+
+	if err != nil {
+		return internalAws.StackSetOptions{}, err
+	}
+
+	autoDeploy, err := cmd.Flags().GetBool("auto-deploy")
+
+	if err != nil {
+		return internalAws.StackSetOptions{}, err
+	}
+
+	retainOnRemoval, err := cmd.Flags().GetBool("retain-on-removal")
+
+	if err != nil {
+		return internalAws.StackSetOptions{}, err
+	}
+
+	delegatedAdmin, err := cmd.Flags().GetBool("delegated-admin")
+
+	if err != nil {
+		return internalAws.StackSetOptions{}, err
+	}
+
+	if len(ouIDs) == 0 {
+		return internalAws.StackSetOptions{}, fmt.Errorf("--ou-id is required with --org, use the root ID to deploy to the whole organization")
+	}
+
+	if len(regions) == 0 {
+		regions = []string{region}
+	}
+
+	// IAM is global, a second region would create the same role name again in every account and fail.
+	if len(regions) > 1 {
+		return internalAws.StackSetOptions{}, fmt.Errorf("the role is global, --regions takes one region, got %s", strings.Join(regions, ", "))
+	}
+
+	if retainOnRemoval && !autoDeploy {
+		return internalAws.StackSetOptions{}, fmt.Errorf("--retain-on-removal requires --auto-deploy")
+	}
+
+	return internalAws.StackSetOptions{
+		OrganizationalUnitIDs:        ouIDs,
+		AccountIDs:                   accountIDs,
+		Regions:                      regions,
+		AutoDeploy:                   autoDeploy,
+		RetainStacksOnAccountRemoval: retainOnRemoval,
+		DelegatedAdmin:               delegatedAdmin,
+	}, nil
+}
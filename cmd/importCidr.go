@@ -8,8 +8,6 @@ import (
 
 	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -23,10 +21,12 @@ var importCidrCmd = &cobra.Command{
 		vpcId, err := cmd.Flags().GetString("vpc-id")
 		account, err := cmd.Flags().GetString("account-id")
 		roleName, err := cmd.Flags().GetString("assume-role")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		includeSubnets, err := cmd.Flags().GetBool("include-subnets")
+		tagVpc, err := cmd.Flags().GetBool("tag-vpc")
 		tableName := viper.GetString("dynamodb.tableName")
 		logger := logging.NewLogger(logLevel)
 		ctx := context.TODO()
-		assumedRoleArn := "arn:aws:iam::" + account + ":role/earnix/" + roleName
 		region := viper.GetString("global.region")
 
 		if region == "" {
@@ -41,21 +41,21 @@ var importCidrCmd = &cobra.Command{
 			logger.Fatal("vpcId is not set")
 		}
 
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		cfg, err := loadAWSConfig(ctx, region)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
 		logger.Debug("Initializing EC2 client")
-		hubEC2Client, err := internalAws.GetEc2Client(cfg)
+		hubEC2Client, err := internalAws.GetEc2Client(cfg, serviceEndpoint("ec2"))
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
 		logger.Debug("Initializing DynamoDB client")
-		hubDynamoClient, err := internalAws.GetDynamoDBClient(cfg)
+		hubDynamoClient, err := dynamoDBClient(cfg)
 
 		logger.Debug("Initializing STS client")
 
@@ -64,15 +64,35 @@ var importCidrCmd = &cobra.Command{
 		}
 
 		// Initialize the STS client
-		hubStsClient, err := internalAws.GetStsClient(cfg)
+		hubStsClient, err := internalAws.GetStsClient(cfg, serviceEndpoint("sts"))
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
+		actor, err := resolveActor(ctx, cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		ec2Client := hubEC2Client
+
 		if account != "" {
+			assumedRoleArn, err := roleArnForAccount(account, roleName)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
 			logger.Debugf("Assuming role for account %s, role %s", account, assumedRoleArn)
-			assumedRoleCfg, err := internalAws.AssumeRole(cfg, hubStsClient, assumedRoleArn)
+			assumeOpts, err := assumeRoleOptions()
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			assumedRoleCfg, err := internalAws.AssumeRole(cfg, hubStsClient, assumedRoleArn, assumeOpts)
 
 			if err != nil {
 				logger.Fatal(err)
@@ -81,42 +101,85 @@ var importCidrCmd = &cobra.Command{
 			logger.Debugf("%s Role assumed successfully", assumedRoleArn)
 
 			// Extract credentials from the assumed role output
-			spokeEC2Client := ec2.NewFromConfig(assumedRoleCfg)
+			ec2Client, err = internalAws.GetEc2Client(assumedRoleCfg, serviceEndpoint("ec2"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+		}
+
+		logger.Debugf("Getting VPC info for vpc %s", vpcId)
+		vpcInfo, err := internalAws.GetVpcInfo(ctx, ec2Client, vpcId, actor)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
 
-			logger.Debugf("Getting VPC info for vpc %s", vpcId)
-			vpcInfo, err := internalAws.GetVpcInfo(spokeEC2Client, vpcId)
+		logger.Debugf("vpcInfo %v", vpcInfo)
+
+		var subnets []internalAws.SubnetInfo
+
+		if includeSubnets {
+			logger.Debugf("Getting subnets for vpc %s", vpcId)
+			subnets, err = internalAws.GetSubnetInfo(ec2Client, vpcId)
 
 			if err != nil {
 				logger.Fatal(err)
 			}
 
-			logger.Debugf("vpcInfo %v", vpcInfo)
+			logger.Debugf("Found %d subnets in vpc %s", len(subnets), vpcId)
+		}
+
+		if dryRun {
+			logger.Debugf("Planning import of CIDR blocks for vpc %s", vpcId)
+			change, err := internalAws.PlanImportCIDR(ctx, hubDynamoClient, tableName, vpcInfo)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
 
-			logger.Debugf("Importing CIDR blocks for vpc %s", vpcId)
-			err = internalAws.PushToDynamoDB(ctx, hubDynamoClient, vpcInfo, tableName)
+			subnetChanges, err := internalAws.PlanImportSubnets(ctx, hubDynamoClient, tableName, vpcInfo, subnets)
 
 			if err != nil {
 				logger.Fatal(err)
 			}
 
-			logger.Infof("CIDR block imported successfully")
+			printPlan(logger, internalAws.Plan{
+				Command:   cmd.CommandPath(),
+				TableName: tableName,
+				Changes:   append([]internalAws.PlannedChange{change}, subnetChanges...),
+			})
+
+			return
 		}
 
-		logger.Debug("Getting VPC info")
-		vpcInfo, err := internalAws.GetVpcInfo(hubEC2Client, vpcId)
+		logger.Debugf("Importing CIDR blocks for vpc %s", vpcId)
+		imported, err := internalAws.PushToDynamoDB(ctx, hubDynamoClient, vpcInfo, tableName)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		logger.Debug("Importing CIDR blocks")
-		err = internalAws.PushToDynamoDB(ctx, hubDynamoClient, vpcInfo, tableName)
+		if imported {
+			logger.Infof("CIDR block imported successfully")
+		} else {
+			logger.Infof("CIDR block %s was already imported for vpc %s", vpcInfo.CIDR, vpcInfo.VpcID)
+		}
 
-		if err != nil {
-			logger.Fatal(err)
+		if tagVpc {
+			tagVpcWithReservation(ctx, logger, ec2Client, hubDynamoClient, tableName, vpcInfo.CIDR)
 		}
 
-		logger.Infof("CIDR block imported successfully")
+		if includeSubnets {
+			logger.Debugf("Importing subnets for vpc %s", vpcId)
+			err = internalAws.PushSubnetsToDynamoDB(ctx, hubDynamoClient, vpcInfo, subnets, tableName, logger)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("%d subnets imported successfully", len(subnets))
+		}
 	},
 }
 
@@ -135,5 +198,8 @@ func init() {
 	// importCidrCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	importCidrCmd.Flags().StringP("vpc-id", "v", "", "The VPC ID to import CIDR blocks from")
 	importCidrCmd.Flags().StringP("account-id", "a", "", "The AWS account ID to import CIDR blocks from")
-	importCidrCmd.Flags().String("assume-role", "", "The role name to assume")
+	importCidrCmd.Flags().String("assume-role", "", "The role name to assume (default is iam.assumedRoleName)")
+	importCidrCmd.Flags().Bool("include-subnets", false, "Also import the VPC's subnets as child reservations")
+	importCidrCmd.Flags().Bool("tag-vpc", false, "Tag the VPC with its reservation ID, pool and owner")
+	importCidrCmd.Flags().Bool("dry-run", false, "Print the import plan without writing to DynamoDB")
 }
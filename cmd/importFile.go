@@ -0,0 +1,114 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// importFileCmd represents the importFile command
+var importFileCmd = &cobra.Command{
+	Use:   "import-file <file>",
+	Short: "Import on-prem, partner and VPN ranges from a CSV or YAML file",
+	Long: `Import address ranges that are used outside of AWS as reservations.
+
+CSV files need a header row with a cidr and source column, and optionally name and description.
+YAML files need a top level ranges list with the same keys. The source must be one of onprem,
+partner or vpn. External ranges block allocation like any other reservation, but they are never
+checked against AWS. Ranges that overlap an existing reservation, or an earlier range of the file,
+are skipped with a warning, and shown as blocked by --dry-run.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		logger.Debugf("Loading external ranges from %s", args[0])
+		ranges, err := helpers.LoadExternalRanges(args[0])
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		dynamoClient, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		actor, err := resolveActor(ctx, cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		reservations := internalAws.ExternalRangeReservations(ranges, actor)
+
+		if dryRun {
+			logger.Debug("Planning external range import")
+			changes, err := internalAws.PlanImportReservations(ctx, dynamoClient, tableName, reservations)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			printPlan(logger, internalAws.Plan{
+				Command:   cmd.CommandPath(),
+				TableName: tableName,
+				Changes:   changes,
+			})
+
+			return
+		}
+
+		logger.Debugf("Importing %d external ranges", len(reservations))
+		imported, err := internalAws.ImportReservations(ctx, dynamoClient, tableName, reservations, logger)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("%d of %d external ranges imported successfully", imported, len(reservations))
+	},
+}
+
+func init() {
+	dynamodbCmd.AddCommand(importFileCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// importFileCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// importFileCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	importFileCmd.Flags().Bool("dry-run", false, "Print the import plan without writing to DynamoDB")
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamExportCmd represents the ipamExport command
+var ipamExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Provision reservations as allocations in an IPAM pool",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		poolID, err := cmd.Flags().GetString("pool-id")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		ipamRegion, err := cmd.Flags().GetString("ipam-region")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		if ipamRegion == "" {
+			ipamRegion = region
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		dynamoClient, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		ipamCfg := cfg.Copy()
+		ipamCfg.Region = ipamRegion
+
+		logger.Debug("Initializing EC2 client")
+		ec2Client, err := internalAws.GetEc2Client(ipamCfg, serviceEndpoint("ec2"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		pools, err := internalAws.GetIpamPools(ctx, ec2Client, []string{poolID})
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if len(pools) == 0 {
+			logger.Fatalf("IPAM pool %s is not an IPv4 pool", poolID)
+		}
+
+		logger.Debugf("Exporting reservations to IPAM pool %s", poolID)
+		allocated, err := internalAws.ExportToIpam(ctx, ec2Client, dynamoClient, tableName, pools[0], dryRun, logger)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		for _, cidr := range allocated {
+			if dryRun {
+				logger.Infof("Dry run: CIDR %s would be allocated in IPAM pool %s", cidr, poolID)
+			} else {
+				logger.Infof("CIDR %s allocated in IPAM pool %s", cidr, poolID)
+			}
+		}
+
+		logger.Infof("%d reservations exported to IPAM pool %s", len(allocated), poolID)
+	},
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamExportCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ipamExportCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ipamExportCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	ipamExportCmd.Flags().String("pool-id", "", "The IPAM pool to allocate the reservations in")
+	ipamExportCmd.MarkFlagRequired("pool-id")
+	ipamExportCmd.Flags().Bool("dry-run", false, "Check the allocations with EC2 without making them")
+}
@@ -0,0 +1,171 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ipamImportCmd represents the ipamImport command
+var ipamImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import IPAM pools, allocations and discovered VPCs as reservations",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		poolIDs, err := cmd.Flags().GetStringSlice("pool-id")
+		resourceDiscoveryID, err := cmd.Flags().GetString("resource-discovery-id")
+		resourceRegions, err := cmd.Flags().GetStringSlice("resource-region")
+		updatePools, err := cmd.Flags().GetBool("update-pools")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		ipamRegion, err := cmd.Flags().GetString("ipam-region")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		if ipamRegion == "" {
+			ipamRegion = region
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		dynamoClient, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		ipamCfg := cfg.Copy()
+		ipamCfg.Region = ipamRegion
+
+		logger.Debug("Initializing EC2 client")
+		ec2Client, err := internalAws.GetEc2Client(ipamCfg, serviceEndpoint("ec2"))
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Describing IPAM pools")
+		pools, err := internalAws.GetIpamPools(ctx, ec2Client, poolIDs)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		var reservations []internalAws.Reservation
+		seen := map[string]bool{}
+
+		for _, pool := range pools {
+			logger.Debugf("Getting allocations of IPAM pool %s", pool.PoolID)
+			poolReservations, err := internalAws.GetIpamPoolReservations(ctx, ec2Client, pool)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			for _, reservation := range poolReservations {
+				if !seen[reservation.CIDR] {
+					seen[reservation.CIDR] = true
+					reservations = append(reservations, reservation)
+				}
+			}
+		}
+
+		if resourceDiscoveryID != "" {
+			if len(resourceRegions) == 0 {
+				resourceRegions = []string{region}
+			}
+
+			for _, resourceRegion := range resourceRegions {
+				logger.Debugf("Getting VPCs discovered by %s in %s", resourceDiscoveryID, resourceRegion)
+				discovered, err := internalAws.GetIpamDiscoveredReservations(ctx, ec2Client, resourceDiscoveryID, resourceRegion)
+
+				if err != nil {
+					logger.Fatal(err)
+				}
+
+				for _, reservation := range discovered {
+					if !seen[reservation.CIDR] {
+						seen[reservation.CIDR] = true
+						reservations = append(reservations, reservation)
+					}
+				}
+			}
+		}
+
+		if dryRun {
+			logger.Debug("Planning IPAM import")
+			changes, err := internalAws.PlanImportReservations(ctx, dynamoClient, tableName, reservations)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			printPlan(logger, internalAws.Plan{
+				Command:   cmd.CommandPath(),
+				TableName: tableName,
+				Changes:   changes,
+			})
+
+			return
+		}
+
+		logger.Debugf("Importing %d reservations from IPAM", len(reservations))
+		imported, err := internalAws.ImportReservations(ctx, dynamoClient, tableName, reservations, logger)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("%d of %d IPAM reservations imported successfully", imported, len(reservations))
+
+		if updatePools {
+			viper.Set("pools", internalAws.IpamPoolsAsPools(pools))
+
+			if err := viper.WriteConfig(); err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("Pools written to config file %s", viper.ConfigFileUsed())
+		}
+	},
+}
+
+func init() {
+	ipamCmd.AddCommand(ipamImportCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ipamImportCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ipamImportCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	ipamImportCmd.Flags().StringSlice("pool-id", []string{}, "The IPAM pools to import allocations from (default is every IPv4 pool)")
+	ipamImportCmd.Flags().String("resource-discovery-id", "", "An IPAM resource discovery to import discovered VPCs from")
+	ipamImportCmd.Flags().StringSlice("resource-region", []string{}, "The regions to import discovered VPCs from (default is the configured region)")
+	ipamImportCmd.Flags().Bool("update-pools", false, "Replace the pools in the config file with the CIDRs provisioned to the IPAM pools")
+	ipamImportCmd.Flags().Bool("dry-run", false, "Print the import plan without writing to DynamoDB")
+}
@@ -8,7 +8,6 @@ import (
 
 	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -34,14 +33,14 @@ var listCidrCmd = &cobra.Command{
 			logger.Fatal("AWS_REGION environment variable is not set")
 		}
 
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		cfg, err := loadAWSConfig(ctx, region)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
 		logger.Debug("Initializing DynamoDB client")
-		client, err := internalAws.GetDynamoDBClient(cfg)
+		client, err := dynamoDBClient(cfg)
 
 		if err != nil {
 			logger.Fatal(err)
@@ -0,0 +1,127 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// planSubnetsCmd represents the planSubnets command
+var planSubnetsCmd = &cobra.Command{
+	Use:   "plan-subnets",
+	Short: "Carve a reserved VPC CIDR into per-AZ subnets",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		cidr, err := cmd.Flags().GetString("cidr")
+		azCount, err := cmd.Flags().GetInt("azs")
+		azNames, err := cmd.Flags().GetStringSlice("az-names")
+		tierSpec, err := cmd.Flags().GetString("tiers")
+		record, err := cmd.Flags().GetBool("record")
+
+		if len(azNames) > 0 && cmd.Flags().Changed("azs") && azCount != len(azNames) {
+			logger.Fatalf("azs is %d but %d az-names are given, set only one of them", azCount, len(azNames))
+		}
+
+		if len(azNames) == 0 {
+			for i := 1; i <= azCount; i++ {
+				azNames = append(azNames, fmt.Sprintf("az%d", i))
+			}
+		}
+
+		tiers, err := helpers.ParseSubnetTiers(tierSpec)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Planning subnets for %s across %d availability zones", cidr, len(azNames))
+		subnets, err := helpers.PlanSubnets(cidr, azNames, tiers)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		err = internalAws.PrintSubnetPlan(subnets, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if !record {
+			return
+		}
+
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		client, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		actor, err := resolveActor(ctx, cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debugf("Recording %d subnets as child reservations of %s", len(subnets), cidr)
+		err = internalAws.RecordSubnets(ctx, client, tableName, cidr, subnets, actor, logger)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Infof("%d subnets of %s recorded successfully", len(subnets), cidr)
+	},
+}
+
+func init() {
+	// rootCmd.AddCommand(planSubnetsCmd)
+	dynamodbCmd.AddCommand(planSubnetsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// planSubnetsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// planSubnetsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	planSubnetsCmd.Flags().StringP("cidr", "c", "", "The VPC CIDR block to carve into subnets")
+	planSubnetsCmd.MarkFlagRequired("cidr")
+	planSubnetsCmd.Flags().Int("azs", 3, "The number of availability zones to plan subnets for")
+	planSubnetsCmd.Flags().StringSlice("az-names", []string{}, "The availability zone names to use (default is az1, az2, ...)")
+	planSubnetsCmd.Flags().String("tiers", "", "The subnet tiers and their prefix sizes, e.g. public:/24,private:/19,db:/24")
+	planSubnetsCmd.MarkFlagRequired("tiers")
+	planSubnetsCmd.Flags().Bool("record", false, "Record the subnets as child reservations of the VPC reservation")
+}
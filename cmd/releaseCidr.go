@@ -8,7 +8,6 @@ import (
 
 	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -23,27 +22,51 @@ var releaseCidrCmd = &cobra.Command{
 		logLevel, err := cmd.Flags().GetString("log-level")
 		logger := logging.NewLogger(logLevel)
 		ctx := context.TODO()
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
 		region := viper.GetString("global.region")
 
 		if region == "" {
 			logger.Fatal("AWS_REGION environment variable is not set")
 		}
 
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		cfg, err := loadAWSConfig(ctx, region)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
 		logger.Debug("Initializing DynamoDB client")
-		client, err := internalAws.GetDynamoDBClient(cfg)
+		client, err := dynamoDBClient(cfg)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
+		if dryRun {
+			logger.Debug("Planning CIDR release")
+			changes, err := internalAws.PlanReleaseCIDR(ctx, client, tableName, cidr)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			printPlan(logger, internalAws.Plan{
+				Command:   cmd.CommandPath(),
+				TableName: tableName,
+				Changes:   changes,
+			})
+
+			return
+		}
+
 		logger.Debug("Releasing CIDR block")
-		err = internalAws.ReleaseCidr(ctx, client, cidr, logger)
+		err = internalAws.ReleaseCidr(ctx, client, tableName, cidr, logger)
 
 		if err != nil {
 			logger.Fatal(err)
@@ -68,4 +91,5 @@ func init() {
 	// releaseCidrCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	releaseCidrCmd.Flags().StringSliceVarP(&cidr, "cidr", "c", []string{}, "The CIDR block to release")
 	releaseCidrCmd.MarkFlagRequired("cidr")
+	releaseCidrCmd.Flags().Bool("dry-run", false, "Print the release plan without deleting from DynamoDB")
 }
@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// reportUtilizationCmd represents the reportUtilization command
+var reportUtilizationCmd = &cobra.Command{
+	Use:   "utilization",
+	Short: "Report reserved and free address space per pool",
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		ctx := context.TODO()
+		output := viper.GetString("global.output")
+		poolCidrs, err := cmd.Flags().GetStringSlice("pool")
+		vpcIDs, err := cmd.Flags().GetStringSlice("vpc-id")
+		tableName := viper.GetString("dynamodb.tableName")
+
+		if tableName == "" {
+			logger.Fatal("DDB_TABLE_NAME environment variable is not set")
+		}
+
+		region := viper.GetString("global.region")
+
+		if region == "" {
+			logger.Fatal("AWS_REGION environment variable is not set")
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		logger.Debug("Initializing DynamoDB client")
+		client, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		var report []helpers.Utilization
+
+		if len(vpcIDs) > 0 {
+			logger.Debugf("Computing subnet utilization report for VPCs %v", vpcIDs)
+			report, err = internalAws.VpcUtilizationReport(ctx, client, tableName, vpcIDs)
+		} else {
+			var pools []helpers.Pool
+			pools, err = loadPools(poolCidrs...)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Debug("Computing utilization report")
+			report, err = internalAws.UtilizationReport(ctx, client, tableName, pools)
+		}
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		err = internalAws.PrintUtilizationReport(report, output)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(reportUtilizationCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// reportUtilizationCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// reportUtilizationCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	reportUtilizationCmd.Flags().StringSlice("vpc-id", []string{}, "Report how much of these VPCs' own CIDRs is used by their subnets instead of pool utilization")
+	reportUtilizationCmd.Flags().StringSlice("pool", []string{}, "The base CIDRs to report on (default is the pools from the config file)")
+}
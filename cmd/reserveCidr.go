@@ -9,7 +9,6 @@ import (
 	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -26,6 +25,10 @@ var reserveCidrCmd = &cobra.Command{
 		ctx := context.TODO()
 		logger := logging.NewLogger(logLevel)
 		autoGenerate, err := cmd.Flags().GetBool("auto-generate")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		count, err := cmd.Flags().GetInt("count")
+		requestFile, err := cmd.Flags().GetString("file")
+		tagVpc, err := cmd.Flags().GetBool("tag-vpc")
 		tableName := viper.GetString("dynamodb.tableName")
 
 		if tableName == "" {
@@ -38,27 +41,82 @@ var reserveCidrCmd = &cobra.Command{
 			logger.Fatal("AWS_REGION environment variable is not set")
 		}
 
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+		if count < 1 {
+			logger.Fatalf("count must be at least 1, got %d", count)
+		}
+
+		cfg, err := loadAWSConfig(ctx, region)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
 		logger.Debug("Initializing DynamoDB client")
-		client, err := internalAws.GetDynamoDBClient(cfg)
+		client, err := dynamoDBClient(cfg)
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		actor, err := resolveActor(ctx, cfg)
 
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		if autoGenerate {
+		var requests []helpers.ReservationRequest
+
+		if requestFile != "" {
+			// The file sets the CIDRs and VPCs of every reservation itself
+			for _, flag := range []string{"cidr", "count", "vpc-id", "vpc-name", "auto-generate", "base-cidr", "prefix-size"} {
+				if cmd.Flags().Changed(flag) {
+					logger.Fatalf("the %s flag can't be used with the file flag", flag)
+				}
+			}
+
+			logger.Debugf("Loading reservation requests from %s", requestFile)
+			requests, err = helpers.LoadReservationRequests(requestFile)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+		} else if autoGenerate {
 			baseCidr, err := cmd.Flags().GetString("base-cidr")
 			prefixSize, err := cmd.Flags().GetInt("prefix-size")
 
-			if baseCidr == "" && prefixSize == 0 {
+			if baseCidr == "" || prefixSize == 0 {
 				logger.Fatal("base-cidr and prefix-size flags are required when auto-generate flag is set")
 			}
 
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			for i := 0; i < count; i++ {
+				requests = append(requests, helpers.ReservationRequest{
+					BaseCIDR:   baseCidr,
+					PrefixSize: prefixSize,
+					VpcID:      vpcID,
+					VpcName:    vpcName,
+				})
+			}
+		} else {
+			if cidr == "" {
+				logger.Fatal("cidr flag is required when auto-generate flag is not set")
+			}
+
+			if count != 1 {
+				logger.Fatal("count flag requires the auto-generate flag")
+			}
+
+			requests = append(requests, helpers.ReservationRequest{
+				CIDR:    cidr,
+				VpcID:   vpcID,
+				VpcName: vpcName,
+			})
+		}
+
+		if autoGenerate || requestFile != "" {
 			existingCidrs, err := internalAws.FetchExistingCIDRs(client, tableName)
 
 			logger.Debugf("Fetching existing CIDRs from DynamoDB table %v", existingCidrs)
@@ -68,24 +126,72 @@ var reserveCidrCmd = &cobra.Command{
 			}
 
 			logger.Debug("Generating CIDR")
-			cidr, err = helpers.GenerateCIDR(existingCidrs, baseCidr, prefixSize)
+			requests, err = helpers.AllocateCIDRs(existingCidrs, requests)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			for _, request := range requests {
+				if request.BaseCIDR != "" {
+					logger.Infof("Generated CIDR: %s", request.CIDR)
+				}
+			}
+		}
+
+		if dryRun {
+			logger.Debug("Planning CIDR reservation")
+			changes, err := internalAws.PlanReserveCIDRs(ctx, client, tableName, requests, actor)
 
 			if err != nil {
 				logger.Fatal(err)
 			}
 
-			logger.Infof("Generated CIDR: %s", cidr)
+			printPlan(logger, internalAws.Plan{
+				Command:   cmd.CommandPath(),
+				TableName: tableName,
+				Changes:   changes,
+			})
 
+			return
 		}
 
-		logger.Debug("Reserving CIDR")
-		err = internalAws.ReserveCIDR(ctx, client, cidr, vpcID, vpcName, logger)
+		if len(requests) == 1 {
+			logger.Debug("Reserving CIDR")
+			err = internalAws.ReserveCIDR(ctx, client, tableName, requests[0].CIDR, requests[0].VpcID, requests[0].VpcName, actor, logger)
 
-		if err != nil {
-			logger.Fatal(err)
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("CIDR %s reserved successfully", requests[0].CIDR)
+		} else {
+			logger.Debugf("Reserving %d CIDRs in one transaction", len(requests))
+			err = internalAws.ReserveCIDRs(ctx, client, tableName, requests, actor, logger)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			for _, request := range requests {
+				logger.Infof("CIDR %s reserved successfully", request.CIDR)
+			}
 		}
 
-		logger.Infof("CIDR %s reserved successfully", cidr)
+		if tagVpc {
+			logger.Debug("Initializing EC2 client")
+			ec2Client, err := internalAws.GetEc2Client(cfg, serviceEndpoint("ec2"))
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			for _, request := range requests {
+				if request.VpcID != "" {
+					tagVpcWithReservation(ctx, logger, ec2Client, client, tableName, request.CIDR)
+				}
+			}
+		}
 	},
 }
 
@@ -103,10 +209,13 @@ func init() {
 	// is called directly, e.g.:
 	// reserveCidrCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	reserveCidrCmd.Flags().StringP("cidr", "c", "", "The CIDR block to reserve")
-	reserveCidrCmd.MarkFlagRequired("cidr")
 	reserveCidrCmd.Flags().String("vpc-id", "", "The ID of the VPC to associate with the CIDR block")
 	reserveCidrCmd.Flags().String("vpc-name", "", "The name of the VPC to associate with the CIDR block")
 	reserveCidrCmd.Flags().Bool("auto-generate", false, "Automatically generate a CIDR block")
 	reserveCidrCmd.Flags().String("base-cidr", "", "The base CIDR block to use when auto-generating a CIDR block")
 	reserveCidrCmd.Flags().Int("prefix-size", 16, "The prefix size to use when auto-generating a CIDR block")
+	reserveCidrCmd.Flags().Int("count", 1, "The number of CIDR blocks to auto-generate and reserve together")
+	reserveCidrCmd.Flags().StringP("file", "f", "", "A YAML file with a list of reservations to make together")
+	reserveCidrCmd.Flags().Bool("tag-vpc", false, "Tag the linked VPCs with their reservation ID, pool and owner")
+	reserveCidrCmd.Flags().Bool("dry-run", false, "Print the reservation plan without writing to DynamoDB")
 }
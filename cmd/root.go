@@ -4,10 +4,22 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
+	internalAws "github.com/asafdavid23/vpc-cidr-manager/internal/aws"
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
 	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -38,6 +50,14 @@ func Execute() {
 }
 
 func init() {
+	viper.SetDefault("iam.partition", "aws")
+	viper.SetDefault("iam.rolePath", "/earnix/")
+	viper.SetDefault("iam.roleArnTemplate", helpers.DefaultRoleArnTemplate)
+	viper.SetDefault("iam.sessionName", "vpc-cidr-manager")
+	viper.SetDefault("dynamodb.billingMode", helpers.BillingModeProvisioned)
+	viper.SetDefault("dynamodb.readCapacity", 1)
+	viper.SetDefault("dynamodb.writeCapacity", 1)
+
 	initConfig()
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
@@ -46,7 +66,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.vpc-cidr-manager.yaml)")
 	rootCmd.PersistentFlags().String("log-level", "info", "Set the log level (debug, info, warn, error, fatal)")
 	rootCmd.PersistentFlags().Bool("version", false, "Display the version of this CLI tool")
-	rootCmd.PersistentFlags().String("output", "table", "Output type table/json/yaml")
+	rootCmd.PersistentFlags().String("output", "table", "Output type table/json/yaml/markdown")
+	rootCmd.PersistentFlags().String("actor", "", "The name recorded as the owner of reservations (default is the caller identity)")
+	rootCmd.PersistentFlags().String("endpoint-url", "", "Send every AWS request to this endpoint, e.g. LocalStack (default is global.endpointURL)")
+	rootCmd.PersistentFlags().Bool("local-credentials", false, "Use dummy credentials instead of the AWS credential chain, for local endpoints (default is global.localCredentials)")
+	rootCmd.PersistentFlags().String("home-region", "", "The table replica reservations are read from and written to (default is dynamodb.homeRegion)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -55,6 +79,10 @@ func init() {
 	viper.BindPFlag("global.logLevel", rootCmd.PersistentFlags().Lookup("log-level"))
 	viper.BindPFlag("global.output", rootCmd.PersistentFlags().Lookup("output"))
 	viper.BindPFlag("global.region", rootCmd.PersistentFlags().Lookup("region"))
+	viper.BindPFlag("global.actor", rootCmd.PersistentFlags().Lookup("actor"))
+	viper.BindPFlag("global.endpointURL", rootCmd.PersistentFlags().Lookup("endpoint-url"))
+	viper.BindPFlag("global.localCredentials", rootCmd.PersistentFlags().Lookup("local-credentials"))
+	viper.BindPFlag("dynamodb.homeRegion", rootCmd.PersistentFlags().Lookup("home-region"))
 }
 
 func initConfig() {
@@ -84,3 +112,205 @@ func initConfig() {
 		logger.Printf("Using config file: %s", viper.ConfigFileUsed())
 	}
 }
+
+// printPlan prints a dry-run plan and exits non-zero if the real command would fail.
+func printPlan(logger *log.Logger, plan internalAws.Plan) {
+	err := internalAws.PrintPlan(plan, viper.GetString("global.output"))
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if plan.Blocked() {
+		logger.Fatal("Dry run found conflicts, no changes would be made")
+	}
+}
+
+// loadPools returns the pools from the config file, or the given pool CIDRs when any are set.
+func loadPools(poolCIDRs ...string) ([]helpers.Pool, error) {
+	var pools []helpers.Pool
+
+	for _, poolCIDR := range poolCIDRs {
+		if poolCIDR != "" {
+			pools = append(pools, helpers.Pool{Name: poolCIDR, CIDR: poolCIDR})
+		}
+	}
+
+	if len(pools) > 0 {
+		return pools, nil
+	}
+
+	if err := viper.UnmarshalKey("pools", &pools); err != nil {
+		return nil, fmt.Errorf("failed to read pools from config: %w", err)
+	}
+
+	return pools, nil
+}
+
+// tagVpcWithReservation tags the VPC linked to the reservation for cidr with the reservation metadata.
+func tagVpcWithReservation(ctx context.Context, logger *log.Logger, ec2Client *ec2.Client, dynamoClient *dynamodb.Client, tableName string, cidr string) {
+	pools, err := loadPools()
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	reservation, err := internalAws.GetReservation(ctx, dynamoClient, tableName, cidr)
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if reservation == nil {
+		logger.Fatalf("CIDR %s is not reserved", cidr)
+	}
+
+	logger.Debugf("Tagging VPC %s with reservation %s", reservation.VpcID, reservation.CIDR)
+	err = internalAws.TagVpcWithReservation(ctx, ec2Client, *reservation, pools)
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infof("VPC %s tagged with reservation %s", reservation.VpcID, reservation.CIDR)
+}
+
+// roleArnForAccount builds the ARN of the role to assume in account from the iam settings in the config.
+// An empty roleName falls back to iam.assumedRoleName.
+func roleArnForAccount(account string, roleName string) (string, error) {
+	if roleName == "" {
+		roleName = viper.GetString("iam.assumedRoleName")
+	}
+
+	return helpers.RenderRoleArn(viper.GetString("iam.roleArnTemplate"), helpers.RoleArnData{
+		Partition: viper.GetString("iam.partition"),
+		AccountID: account,
+		RolePath:  viper.GetString("iam.rolePath"),
+		RoleName:  roleName,
+	})
+}
+
+// assumeRoleOptions returns the AssumeRole options from the iam settings in the config.
+func assumeRoleOptions() (internalAws.AssumeRoleOptions, error) {
+	opts := internalAws.AssumeRoleOptions{
+		ExternalID:      viper.GetString("iam.externalId"),
+		RoleSessionName: viper.GetString("iam.sessionName"),
+		Duration:        viper.GetDuration("iam.sessionDuration"),
+		MFASerial:       viper.GetString("iam.mfaSerial"),
+	}
+
+	if policyFile := viper.GetString("iam.sessionPolicyFile"); policyFile != "" {
+		policy, err := os.ReadFile(policyFile)
+
+		if err != nil {
+			return internalAws.AssumeRoleOptions{}, fmt.Errorf("failed to read session policy file: %v", err)
+		}
+
+		if !json.Valid(policy) {
+			return internalAws.AssumeRoleOptions{}, fmt.Errorf("session policy file %s is not valid JSON", policyFile)
+		}
+
+		opts.Policy = string(policy)
+	}
+
+	return opts, nil
+}
+
+// loadAWSConfig loads the AWS config every command uses. global.endpointURL sends every service to
+// one endpoint, such as LocalStack. Endpoints of single services are set on each client, see
+// serviceEndpoint. global.localCredentials replaces the credential chain with dummy credentials,
+// so the CLI runs against local endpoints without an AWS account.
+func loadAWSConfig(ctx context.Context, region string) (aws.Config, error) {
+	optFns := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+	}
+
+	if endpointURL := viper.GetString("global.endpointURL"); endpointURL != "" {
+		optFns = append(optFns, config.WithBaseEndpoint(endpointURL))
+	}
+
+	if viper.GetBool("global.localCredentials") {
+		optFns = append(optFns, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")))
+	}
+
+	return config.LoadDefaultConfig(ctx, optFns...)
+}
+
+// serviceEndpoint returns the endpoints.<service> override of a service, such as endpoints.dynamodb
+// for DynamoDB Local, or an empty string to use global.endpointURL or the AWS endpoint.
+func serviceEndpoint(service string) string {
+	return viper.GetString("endpoints." + service)
+}
+
+// endpointOverridden reports whether a service is sent to a custom endpoint.
+func endpointOverridden(service string) bool {
+	return viper.GetString("global.endpointURL") != "" || serviceEndpoint(service) != ""
+}
+
+// homeRegion returns the region of the table replica reservations are read from and written to.
+// With a global table every write goes to a single replica, so the conditional writes that keep
+// reservations from overlapping are evaluated in one place and can't race with writes made in
+// another region. Every user has to pick the same replica, so with more than one replica region the
+// home region must be set explicitly rather than depend on the order regions are listed in.
+func homeRegion() (string, error) {
+	if region := viper.GetString("dynamodb.homeRegion"); region != "" {
+		return region, nil
+	}
+
+	replicaRegions := viper.GetStringSlice("dynamodb.replicaRegions")
+
+	switch len(replicaRegions) {
+	case 0:
+		return viper.GetString("global.region"), nil
+	case 1:
+		return replicaRegions[0], nil
+	default:
+		return "", fmt.Errorf("dynamodb.homeRegion is required with more than one replica region, set it to one of %s", strings.Join(replicaRegions, ", "))
+	}
+}
+
+// dynamoDBClient returns a client for the reservations table in the home region.
+func dynamoDBClient(cfg aws.Config) (*dynamodb.Client, error) {
+	region, err := homeRegion()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cfg = cfg.Copy()
+	cfg.Region = region
+
+	return internalAws.GetDynamoDBClient(cfg, serviceEndpoint("dynamodb"))
+}
+
+// resolveActor returns the name recorded as the owner of reservations: the --actor flag when it is set,
+// otherwise the user, role session or federated user name of the caller identity.
+func resolveActor(ctx context.Context, cfg aws.Config) (string, error) {
+	if actor := viper.GetString("global.actor"); actor != "" {
+		return actor, nil
+	}
+
+	stsClient, err := internalAws.GetStsClient(cfg, serviceEndpoint("sts"))
+
+	if err != nil {
+		return "", err
+	}
+
+	identity, err := internalAws.ResolveIdentity(ctx, stsClient)
+
+	if err != nil {
+		return "", err
+	}
+
+	return identity.Name, nil
+}
+
+// confirm asks the user a yes/no question on the terminal, answers other than yes count as no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
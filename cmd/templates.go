@@ -0,0 +1,30 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// templatesCmd represents the templates command
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Manage the built-in CloudFormation templates",
+	Run: func(cmd *cobra.Command, args []string) {
+	},
+}
+
+func init() {
+	iaacCmd.AddCommand(templatesCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// templatesCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// templatesCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
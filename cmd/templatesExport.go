@@ -0,0 +1,78 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/logging"
+	"github.com/asafdavid23/vpc-cidr-manager/templates"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// templatesExportCmd represents the templatesExport command
+var templatesExportCmd = &cobra.Command{
+	Use:   "export [template...]",
+	Short: "Write the built-in CloudFormation and Terraform templates to a directory",
+	Long: `Write the built-in CloudFormation and Terraform templates to a directory, so they can be
+customized and passed back with --template. Without arguments every built-in template is exported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		logLevel := viper.GetString("global.logLevel")
+		logger := logging.NewLogger(logLevel)
+		dir, err := cmd.Flags().GetString("dir")
+		force, err := cmd.Flags().GetBool("force")
+
+		names := args
+
+		if len(names) == 0 {
+			names, err = templates.Names()
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+		}
+
+		for _, name := range names {
+			content, err := templates.Read(name)
+
+			if err != nil {
+				logger.Fatal(err)
+			}
+
+			path := filepath.Join(dir, name)
+
+			if _, err := os.Stat(path); err == nil && !force {
+				logger.Fatalf("%s already exists, use --force to overwrite it", path)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				logger.Fatal(err)
+			}
+
+			if err := os.WriteFile(path, content, 0o644); err != nil {
+				logger.Fatal(err)
+			}
+
+			logger.Infof("Template %s exported to %s", name, path)
+		}
+	},
+}
+
+func init() {
+	templatesCmd.AddCommand(templatesExportCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// templatesExportCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// templatesExportCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	templatesExportCmd.Flags().StringP("dir", "d", "templates", "The directory to write the templates to")
+	templatesExportCmd.Flags().Bool("force", false, "Overwrite templates that already exist")
+}
@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// GetVpcForCIDR describes a VPC and checks that cidr is one of its associated CIDR blocks.
+func GetVpcForCIDR(ctx context.Context, client *ec2.Client, vpcID string, cidr string) (VPCInfo, error) {
+	_, network, err := net.ParseCIDR(cidr)
+
+	if err != nil {
+		return VPCInfo{}, fmt.Errorf("%w", err)
+	}
+
+	output, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpcID},
+	})
+
+	if err != nil {
+		return VPCInfo{}, fmt.Errorf("%v", err)
+	}
+
+	if len(output.Vpcs) == 0 {
+		return VPCInfo{}, fmt.Errorf("VPC with ID %s not found", vpcID)
+	}
+
+	vpc := output.Vpcs[0]
+
+	vpcInfo := VPCInfo{
+		CIDR:      network.String(),
+		AccountID: aws.ToString(vpc.OwnerId),
+		VpcID:     aws.ToString(vpc.VpcId),
+	}
+
+	for _, tag := range vpc.Tags {
+		if aws.ToString(tag.Key) == "Name" {
+			vpcInfo.VpcName = aws.ToString(tag.Value)
+		}
+	}
+
+	var vpcCIDRs []string
+
+	for _, association := range vpc.CidrBlockAssociationSet {
+		if association.CidrBlockState == nil || association.CidrBlockState.State != types.VpcCidrBlockStateCodeAssociated {
+			continue
+		}
+
+		if aws.ToString(association.CidrBlock) == network.String() {
+			return vpcInfo, nil
+		}
+
+		vpcCIDRs = append(vpcCIDRs, aws.ToString(association.CidrBlock))
+	}
+
+	return VPCInfo{}, fmt.Errorf("VPC %s does not have CIDR %s, its CIDRs are %v", vpcID, network.String(), vpcCIDRs)
+}
+
+// PlanAttachVpc computes the update AttachVpc would make to the reservation, without making it.
+func PlanAttachVpc(ctx context.Context, client *dynamodb.Client, tableName string, vpcInfo VPCInfo) (PlannedChange, error) {
+	change := PlannedChange{
+		Action: PlanActionUpdate,
+		CIDR:   vpcInfo.CIDR,
+		Item: map[string]string{
+			"VpcId":     vpcInfo.VpcID,
+			"VpcName":   vpcInfo.VpcName,
+			"AccountID": vpcInfo.AccountID,
+			"Status":    "in-use",
+		},
+	}
+
+	reservation, err := GetReservation(ctx, client, tableName, vpcInfo.CIDR)
+
+	if err != nil {
+		return PlannedChange{}, err
+	}
+
+	change.Exists = reservation != nil
+
+	if err := checkAttachable(reservation, vpcInfo); err != nil {
+		change.Blocked = true
+		change.Reason = err.Error()
+	}
+
+	return change, nil
+}
+
+// AttachVpc links a VPC to an existing reservation of its CIDR and moves the reservation to in-use.
+// A reservation that is already linked to a different VPC is left untouched.
+func AttachVpc(ctx context.Context, client *dynamodb.Client, tableName string, vpcInfo VPCInfo) error {
+	reservation, err := GetReservation(ctx, client, tableName, vpcInfo.CIDR)
+
+	if err != nil {
+		return err
+	}
+
+	if err := checkAttachable(reservation, vpcInfo); err != nil {
+		return err
+	}
+
+	vpcName := vpcInfo.VpcName
+
+	// Keep the name given at reservation time when the VPC has no Name tag
+	if vpcName == "" {
+		vpcName = reservation.VpcName
+	}
+
+	// The condition guards against the reservation being released or linked elsewhere since it was read.
+	// Older versions of import-cidr stored the VPC as VpcID, which is checked too and moved to VpcId.
+	_, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]dynamodbTypes.AttributeValue{
+			"CIDR": &dynamodbTypes.AttributeValueMemberS{Value: vpcInfo.CIDR},
+		},
+		UpdateExpression: aws.String("SET VpcId = :vpc, VpcName = :name, AccountID = :account, #status = :status, #region = :region REMOVE VpcID"),
+		ConditionExpression: aws.String("attribute_exists(CIDR) AND " +
+			"(attribute_not_exists(VpcId) OR VpcId = :empty OR VpcId = :vpc) AND " +
+			"(attribute_not_exists(VpcID) OR VpcID = :empty OR VpcID = :vpc)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+			"#region": "Region",
+		},
+		ExpressionAttributeValues: map[string]dynamodbTypes.AttributeValue{
+			":vpc":     &dynamodbTypes.AttributeValueMemberS{Value: vpcInfo.VpcID},
+			":name":    &dynamodbTypes.AttributeValueMemberS{Value: vpcName},
+			":account": &dynamodbTypes.AttributeValueMemberS{Value: vpcInfo.AccountID},
+			":status":  &dynamodbTypes.AttributeValueMemberS{Value: "in-use"},
+			":empty":   &dynamodbTypes.AttributeValueMemberS{Value: ""},
+			":region":  &dynamodbTypes.AttributeValueMemberS{Value: writeRegion(client)},
+		},
+	})
+
+	if err != nil {
+		var conditionErr *dynamodbTypes.ConditionalCheckFailedException
+
+		if errors.As(err, &conditionErr) {
+			return fmt.Errorf("CIDR %s was released or linked to another VPC while attaching %s", vpcInfo.CIDR, vpcInfo.VpcID)
+		}
+
+		return fmt.Errorf("Got error calling UpdateItem: %v", err)
+	}
+
+	return nil
+}
+
+// checkAttachable checks that reservation can be linked to the VPC in vpcInfo.
+func checkAttachable(reservation *Reservation, vpcInfo VPCInfo) error {
+	switch {
+	case reservation == nil:
+		return fmt.Errorf("CIDR %s is not reserved, reserve it before attaching a VPC", vpcInfo.CIDR)
+	case reservation.IsChild():
+		return fmt.Errorf("CIDR %s is a %s of %s, only top level reservations can be attached to a VPC", vpcInfo.CIDR, reservation.Type, reservation.ParentCIDR)
+	case reservation.IsExternal():
+		return fmt.Errorf("CIDR %s is an external %s range and cannot be attached to a VPC", vpcInfo.CIDR, reservation.Source)
+	case reservation.VpcID != "" && reservation.VpcID != vpcInfo.VpcID:
+		return fmt.Errorf("CIDR %s is already linked to VPC %s", vpcInfo.CIDR, reservation.VpcID)
+	}
+
+	return nil
+}
@@ -0,0 +1,378 @@
+package aws
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v2"
+)
+
+// BackupVersion is the version of the backup file format written by ExportBackup.
+const BackupVersion = 1
+
+// Backup file formats.
+const (
+	BackupFormatJSON   = "json"
+	BackupFormatNDJSON = "ndjson"
+)
+
+// BackupHeader describes a backup. In NDJSON backups it is the first line, followed by one
+// reservation per line.
+type BackupHeader struct {
+	Version    int    `json:"version"`
+	TableName  string `json:"tableName"`
+	ExportedAt string `json:"exportedAt"`
+	ExportedBy string `json:"exportedBy,omitempty"`
+	Count      int    `json:"count"`
+}
+
+// Backup is every reservation of a table, including child and external reservations with the
+// metadata of when and by whom they were reserved.
+type Backup struct {
+	BackupHeader
+	Reservations []Reservation `json:"reservations"`
+}
+
+// SkippedReservation is a reservation of a backup that is not restored, and why.
+type SkippedReservation struct {
+	CIDR   string `json:"cidr" yaml:"cidr"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// ExportBackup reads every reservation of the table into a backup, sorted by CIDR so backups of the
+// same table diff cleanly. A backup is a snapshot of the current reservations, not their history, and
+// only the attributes of Reservation are kept. Other attributes are dropped, such as the VpcID older
+// versions of import-cidr wrote, so attach those VPCs with attach-vpc before exporting.
+func ExportBackup(ctx context.Context, client *dynamodb.Client, tableName string, actor string) (Backup, error) {
+	reservations, err := FetchReservations(ctx, client, tableName)
+
+	if err != nil {
+		return Backup{}, err
+	}
+
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].CIDR < reservations[j].CIDR
+	})
+
+	return Backup{
+		BackupHeader: BackupHeader{
+			Version:    BackupVersion,
+			TableName:  tableName,
+			ExportedAt: time.Now().UTC().Format(time.RFC3339),
+			ExportedBy: actor,
+			Count:      len(reservations),
+		},
+		Reservations: reservations,
+	}, nil
+}
+
+// BackupFormatForPath returns the backup format for a file name, NDJSON for .ndjson and .jsonl files
+// and JSON otherwise.
+func BackupFormatForPath(path string) string {
+	if strings.HasSuffix(path, ".ndjson") || strings.HasSuffix(path, ".jsonl") {
+		return BackupFormatNDJSON
+	}
+
+	return BackupFormatJSON
+}
+
+// MarshalBackup encodes a backup in the given format.
+func MarshalBackup(backup Backup, format string) ([]byte, error) {
+	switch format {
+	case BackupFormatJSON:
+		return json.MarshalIndent(backup, "", "  ")
+
+	case BackupFormatNDJSON:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+
+		if err := encoder.Encode(backup.BackupHeader); err != nil {
+			return nil, err
+		}
+
+		for _, reservation := range backup.Reservations {
+			if err := encoder.Encode(reservation); err != nil {
+				return nil, err
+			}
+		}
+
+		return buf.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported backup format %s, expected %s or %s", format, BackupFormatJSON, BackupFormatNDJSON)
+	}
+}
+
+// UnmarshalBackup decodes a backup. Unknown fields are rejected so that a file that isn't a backup,
+// or was written by a newer version, fails instead of restoring partial reservations.
+func UnmarshalBackup(data []byte, format string) (Backup, error) {
+	var backup Backup
+
+	switch format {
+	case BackupFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&backup); err != nil {
+			return Backup{}, fmt.Errorf("invalid backup: %v", err)
+		}
+
+	case BackupFormatNDJSON:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		line := 0
+		header := false
+
+		for scanner.Scan() {
+			line++
+
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+
+			decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+			decoder.DisallowUnknownFields()
+
+			var err error
+
+			// The header is the first line that isn't blank
+			if !header {
+				err = decoder.Decode(&backup.BackupHeader)
+				header = true
+			} else {
+				var reservation Reservation
+				err = decoder.Decode(&reservation)
+				backup.Reservations = append(backup.Reservations, reservation)
+			}
+
+			if err != nil && err != io.EOF {
+				return Backup{}, fmt.Errorf("invalid backup on line %d: %v", line, err)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return Backup{}, fmt.Errorf("failed to read backup: %v", err)
+		}
+
+	default:
+		return Backup{}, fmt.Errorf("unsupported backup format %s, expected %s or %s", format, BackupFormatJSON, BackupFormatNDJSON)
+	}
+
+	if backup.Version < 1 || backup.Version > BackupVersion {
+		return Backup{}, fmt.Errorf("unsupported backup version %d, expected at most %d", backup.Version, BackupVersion)
+	}
+
+	if backup.Count != len(backup.Reservations) {
+		return Backup{}, fmt.Errorf("backup is incomplete, it has %d of %d reservations", len(backup.Reservations), backup.Count)
+	}
+
+	return backup, nil
+}
+
+// PlanRestore validates the reservations of a backup and returns the ones that can be restored.
+// Reservations with an invalid schema, duplicate CIDRs, or CIDRs that overlap a reservation that is
+// already restored are skipped. Children may overlap their parent but not each other.
+func PlanRestore(backup Backup) ([]Reservation, []SkippedReservation) {
+	var restore []Reservation
+	var skipped []SkippedReservation
+
+	networks := map[string]*net.IPNet{}
+	var topLevel []Reservation
+	var children []Reservation
+
+	for _, reservation := range backup.Reservations {
+		if reason := validateReservation(reservation); reason != "" {
+			skipped = append(skipped, SkippedReservation{CIDR: reservation.CIDR, Reason: reason})
+			continue
+		}
+
+		if reservation.IsChild() {
+			children = append(children, reservation)
+		} else {
+			topLevel = append(topLevel, reservation)
+		}
+	}
+
+	// Parents are restored before their children, so children can be checked against them
+	accept := func(reservation Reservation, siblings func(Reservation) bool) {
+		_, network, _ := net.ParseCIDR(reservation.CIDR)
+
+		if _, ok := networks[reservation.CIDR]; ok {
+			skipped = append(skipped, SkippedReservation{CIDR: reservation.CIDR, Reason: "duplicate CIDR"})
+			return
+		}
+
+		for _, other := range restore {
+			if !siblings(other) {
+				continue
+			}
+
+			if helpers.CIDRsOverlap(network, networks[other.CIDR]) {
+				skipped = append(skipped, SkippedReservation{CIDR: reservation.CIDR, Reason: "overlaps " + other.CIDR})
+				return
+			}
+		}
+
+		networks[reservation.CIDR] = network
+		restore = append(restore, reservation)
+	}
+
+	for _, reservation := range topLevel {
+		accept(reservation, func(other Reservation) bool {
+			return !other.IsChild()
+		})
+	}
+
+	for _, reservation := range children {
+		parent, ok := networks[reservation.ParentCIDR]
+
+		if !ok {
+			skipped = append(skipped, SkippedReservation{CIDR: reservation.CIDR, Reason: "parent " + reservation.ParentCIDR + " is not restored"})
+			continue
+		}
+
+		_, network, _ := net.ParseCIDR(reservation.CIDR)
+
+		if !helpers.ContainsCIDR(parent, network) {
+			skipped = append(skipped, SkippedReservation{CIDR: reservation.CIDR, Reason: "not within parent " + reservation.ParentCIDR})
+			continue
+		}
+
+		accept(reservation, func(other Reservation) bool {
+			return other.ParentCIDR == reservation.ParentCIDR
+		})
+	}
+
+	return restore, skipped
+}
+
+// validateReservation returns why a reservation of a backup is invalid, or an empty string.
+func validateReservation(reservation Reservation) string {
+	if reservation.CIDR == "" {
+		return "missing CIDR"
+	}
+
+	_, network, err := net.ParseCIDR(reservation.CIDR)
+
+	if err != nil {
+		return "invalid CIDR"
+	}
+
+	if network.String() != reservation.CIDR {
+		return "CIDR is not a network address, expected " + network.String()
+	}
+
+	if reservation.IsChild() {
+		if _, _, err := net.ParseCIDR(reservation.ParentCIDR); err != nil {
+			return "invalid parent CIDR " + reservation.ParentCIDR
+		}
+	}
+
+	if reservation.Source != "" && !reservation.IsExternal() {
+		return "unknown source " + reservation.Source
+	}
+
+	if reservation.ReservedAt != "" && !validReservedAt(reservation.ReservedAt) {
+		return "invalid reservedAt " + reservation.ReservedAt
+	}
+
+	return ""
+}
+
+// legacyReservedAtLayout is the default format of time.Time, which reserve-cidr used to write ReservedAt in.
+const legacyReservedAtLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// validReservedAt reports whether value is a reservation time in RFC3339 or the legacy format.
+func validReservedAt(value string) bool {
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return true
+	}
+
+	// time.Now() also printed its monotonic clock reading
+	if i := strings.Index(value, " m="); i >= 0 {
+		value = value[:i]
+	}
+
+	_, err := time.Parse(legacyReservedAtLayout, value)
+
+	return err == nil
+}
+
+// TableIsEmpty reports whether the table has no items.
+func TableIsEmpty(ctx context.Context, client *dynamodb.Client, tableName string) (bool, error) {
+	output, err := client.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+		Limit:     aws.Int32(1),
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to scan table: %w", err)
+	}
+
+	return len(output.Items) == 0, nil
+}
+
+// ReadBackupFile reads a local backup file, "-" reads stdin.
+func ReadBackupFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(path)
+}
+
+// PrintSkippedReservations writes the reservations that were not restored to stdout in the requested output format.
+func PrintSkippedReservations(skipped []SkippedReservation, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(skipped, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(skipped)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"CIDR", "Reason"})
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, s := range skipped {
+			table.Append([]string{s.CIDR, s.Reason})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
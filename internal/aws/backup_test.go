@@ -0,0 +1,181 @@
+package aws
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanRestore(t *testing.T) {
+	tests := []struct {
+		name         string
+		reservations []Reservation
+		restored     []string
+		skipped      []SkippedReservation
+	}{
+		{
+			name: "children are restored after their parent",
+			reservations: []Reservation{
+				{CIDR: "10.0.1.0/24", ParentCIDR: "10.0.0.0/16"},
+				{CIDR: "10.0.0.0/16"},
+				{CIDR: "10.0.2.0/24", ParentCIDR: "10.0.0.0/16"},
+			},
+			restored: []string{"10.0.0.0/16", "10.0.1.0/24", "10.0.2.0/24"},
+		},
+		{
+			name: "children without a restored parent are skipped",
+			reservations: []Reservation{
+				{CIDR: "10.1.1.0/24", ParentCIDR: "10.1.0.0/16"},
+				{CIDR: "10.0.0.0/16"},
+				{CIDR: "10.1.0.0/24", ParentCIDR: "10.0.0.0/16"},
+			},
+			restored: []string{"10.0.0.0/16"},
+			skipped: []SkippedReservation{
+				{CIDR: "10.1.1.0/24", Reason: "parent 10.1.0.0/16 is not restored"},
+				{CIDR: "10.1.0.0/24", Reason: "not within parent 10.0.0.0/16"},
+			},
+		},
+		{
+			name: "overlapping siblings are skipped",
+			reservations: []Reservation{
+				{CIDR: "10.0.0.0/16"},
+				{CIDR: "10.0.0.0/8"},
+				{CIDR: "10.0.0.0/24", ParentCIDR: "10.0.0.0/16"},
+				{CIDR: "10.0.0.0/25", ParentCIDR: "10.0.0.0/16"},
+			},
+			restored: []string{"10.0.0.0/16", "10.0.0.0/24"},
+			skipped: []SkippedReservation{
+				{CIDR: "10.0.0.0/8", Reason: "overlaps 10.0.0.0/16"},
+				{CIDR: "10.0.0.0/25", Reason: "overlaps 10.0.0.0/24"},
+			},
+		},
+		{
+			name: "duplicates are skipped",
+			reservations: []Reservation{
+				{CIDR: "10.0.0.0/16"},
+				{CIDR: "10.0.0.0/16", VpcID: "vpc-2"},
+			},
+			restored: []string{"10.0.0.0/16"},
+			skipped: []SkippedReservation{
+				{CIDR: "10.0.0.0/16", Reason: "duplicate CIDR"},
+			},
+		},
+		{
+			name: "invalid reservations are skipped",
+			reservations: []Reservation{
+				{CIDR: "10.0.0.1/16"},
+				{CIDR: "10.1.0.0/16", Source: "cloud"},
+				{CIDR: "10.2.0.0/16", ReservedAt: "yesterday"},
+				{CIDR: "10.3.0.0/16", ReservedAt: "2025-01-02T03:04:05Z"},
+				{CIDR: "10.4.0.0/16", ReservedAt: "2025-01-02 03:04:05.123456789 +0000 UTC m=+0.012345678"},
+			},
+			restored: []string{"10.3.0.0/16", "10.4.0.0/16"},
+			skipped: []SkippedReservation{
+				{CIDR: "10.0.0.1/16", Reason: "CIDR is not a network address, expected 10.0.0.0/16"},
+				{CIDR: "10.1.0.0/16", Reason: "unknown source cloud"},
+				{CIDR: "10.2.0.0/16", Reason: "invalid reservedAt yesterday"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore, skipped := PlanRestore(Backup{Reservations: tt.reservations})
+
+			var restored []string
+
+			for _, reservation := range restore {
+				restored = append(restored, reservation.CIDR)
+			}
+
+			if !reflect.DeepEqual(restored, tt.restored) {
+				t.Errorf("restored %v, want %v", restored, tt.restored)
+			}
+
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped %v, want %v", skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestUnmarshalBackup(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		count  int
+		err    string
+	}{
+		{
+			name:   "json",
+			format: BackupFormatJSON,
+			data:   `{"version":1,"tableName":"cidrs","exportedAt":"2025-01-02T03:04:05Z","count":1,"reservations":[{"cidr":"10.0.0.0/16"}]}`,
+			count:  1,
+		},
+		{
+			name:   "ndjson",
+			format: BackupFormatNDJSON,
+			data:   "{\"version\":1,\"tableName\":\"cidrs\",\"count\":2}\n{\"cidr\":\"10.0.0.0/16\"}\n\n{\"cidr\":\"10.1.0.0/16\"}\n",
+			count:  2,
+		},
+		{
+			name:   "ndjson header after blank lines",
+			format: BackupFormatNDJSON,
+			data:   "\n  \n{\"version\":1,\"tableName\":\"cidrs\",\"count\":1}\n{\"cidr\":\"10.0.0.0/16\"}\n",
+			count:  1,
+		},
+		{
+			name:   "ndjson header count mismatch",
+			format: BackupFormatNDJSON,
+			data:   "{\"version\":1,\"tableName\":\"cidrs\",\"count\":3}\n{\"cidr\":\"10.0.0.0/16\"}\n{\"cidr\":\"10.1.0.0/16\"}\n",
+			err:    "backup is incomplete, it has 2 of 3 reservations",
+		},
+		{
+			name:   "unknown header field",
+			format: BackupFormatJSON,
+			data:   `{"version":1,"tableName":"cidrs","count":0,"reservations":[],"owner":"me"}`,
+			err:    `unknown field "owner"`,
+		},
+		{
+			name:   "unknown reservation field",
+			format: BackupFormatNDJSON,
+			data:   "{\"version\":1,\"tableName\":\"cidrs\",\"count\":1}\n{\"cidr\":\"10.0.0.0/16\",\"pool\":\"prod\"}\n",
+			err:    "invalid backup on line 2",
+		},
+		{
+			name:   "newer version",
+			format: BackupFormatJSON,
+			data:   `{"version":2,"tableName":"cidrs","count":0,"reservations":[]}`,
+			err:    "unsupported backup version 2",
+		},
+		{
+			name:   "unsupported format",
+			format: "csv",
+			data:   "cidr\n10.0.0.0/16\n",
+			err:    "unsupported backup format csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup, err := UnmarshalBackup([]byte(tt.data), tt.format)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(backup.Reservations) != tt.count {
+				t.Errorf("got %d reservations, want %d", len(backup.Reservations), tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v2"
+)
+
+// CIDRCheck is the result of checking whether a CIDR block is free to reserve.
+type CIDRCheck struct {
+	CIDR          string        `json:"cidr" yaml:"cidr"`
+	Available     bool          `json:"available" yaml:"available"`
+	Pool          *helpers.Pool `json:"pool,omitempty" yaml:"pool,omitempty"`
+	Conflicts     []Reservation `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+	Alternatives  []string      `json:"alternatives,omitempty" yaml:"alternatives,omitempty"`
+	TagMismatches []TagMismatch `json:"tagMismatches,omitempty" yaml:"tagMismatches,omitempty"`
+}
+
+// CheckCIDR reports the reservations that conflict with cidr, the pool that contains it,
+// and up to alternatives free CIDRs of the same size from that pool.
+func CheckCIDR(ctx context.Context, client *dynamodb.Client, tableName string, cidr string, pools []helpers.Pool, alternatives int) (CIDRCheck, error) {
+	_, network, err := net.ParseCIDR(cidr)
+
+	if err != nil {
+		return CIDRCheck{}, fmt.Errorf("%w", err)
+	}
+
+	check := CIDRCheck{
+		CIDR: network.String(),
+	}
+
+	reservations, err := FetchReservations(ctx, client, tableName)
+
+	if err != nil {
+		return CIDRCheck{}, err
+	}
+
+	var existingCIDRs []string
+
+	for _, reservation := range reservations {
+		if reservation.IsChild() {
+			continue
+		}
+
+		existingCIDRs = append(existingCIDRs, reservation.CIDR)
+
+		_, reservedNetwork, err := net.ParseCIDR(reservation.CIDR)
+
+		if err != nil {
+			continue
+		}
+
+		if cidrOverlaps(reservedNetwork, network) {
+			check.Conflicts = append(check.Conflicts, reservation)
+		}
+	}
+
+	check.Available = len(check.Conflicts) == 0
+
+	check.Pool, err = helpers.FindContainingPool(pools, check.CIDR)
+
+	if err != nil {
+		return CIDRCheck{}, err
+	}
+
+	if !check.Available && check.Pool != nil && alternatives > 0 {
+		check.Alternatives, err = helpers.ClosestFreeCIDRs(existingCIDRs, check.Pool.CIDR, check.CIDR, alternatives)
+
+		if err != nil {
+			return CIDRCheck{}, err
+		}
+	}
+
+	return check, nil
+}
+
+// PrintCIDRCheck writes the result of a CIDR check to stdout in the requested output format.
+func PrintCIDRCheck(check CIDRCheck, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(check, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(check)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table":
+		if check.Available {
+			fmt.Printf("CIDR %s is available\n", check.CIDR)
+		} else {
+			fmt.Printf("CIDR %s conflicts with %d reservation(s)\n", check.CIDR, len(check.Conflicts))
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"CIDR", "Account", "VPC ID", "VPC Name", "Reserved By", "Status"})
+
+			for _, conflict := range check.Conflicts {
+				table.Append([]string{
+					conflict.CIDR,
+					conflict.AccountID,
+					conflict.VpcID,
+					conflict.VpcName,
+					conflict.ReservedBy,
+					conflict.Status,
+				})
+			}
+
+			table.Render()
+		}
+
+		if check.Pool != nil {
+			fmt.Printf("Pool: %s (%s)\n", check.Pool.Name, check.Pool.CIDR)
+		} else {
+			fmt.Println("Pool: none of the configured pools contain this CIDR")
+		}
+
+		if len(check.Alternatives) > 0 {
+			fmt.Printf("Closest free alternatives: %s\n", strings.Join(check.Alternatives, ", "))
+		}
+
+		for _, mismatch := range check.TagMismatches {
+			fmt.Printf("VPC %s is recorded for %s but tagged with reservation %s\n", mismatch.VpcID, mismatch.CIDR, mismatch.ReservationID)
+		}
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
@@ -2,16 +2,30 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
 )
 
-func InitializeCFNClient(cfg aws.Config) (*cloudformation.Client, error) {
-	cfnClient := cloudformation.NewFromConfig(cfg)
+// InitializeCFNClient returns a CloudFormation client, sent to endpoint instead of the AWS endpoint when it is set.
+func InitializeCFNClient(cfg aws.Config, endpoint string) (*cloudformation.Client, error) {
+	cfnClient := cloudformation.NewFromConfig(cfg, func(o *cloudformation.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
 
 	return cfnClient, nil
 
@@ -34,21 +48,138 @@ func CreateCFNStack(ctx context.Context, client *cloudformation.Client, stackNam
 	return output, nil
 }
 
-// waitForStackToBeCreated waits for the CloudFormation stack to be created successfully.
-func WaitForStackToBeCreated(ctx context.Context, cfnClient *cloudformation.Client, stackName string) error {
-	// Wait for the stack to reach the 'CREATE_COMPLETE' status
-	waiterCfg := cloudformation.NewStackCreateCompleteWaiter(cfnClient)
-	describeStacksInput := &cloudformation.DescribeStacksInput{
-		StackName: aws.String(stackName),
-	}
+// stackEventPollInterval is how often WaitForStack polls for new stack events.
+const stackEventPollInterval = 5 * time.Second
+
+// WaitForStack streams the stack's events to the logger until the stack reaches a final status or timeout passes.
+// Only events after since are streamed. When the stack operation fails, the error carries the status reason
+// of the first resource that failed.
+func WaitForStack(ctx context.Context, cfnClient *cloudformation.Client, stackName string, since time.Time, timeout time.Duration, logger *log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stack, err := GetCFNStack(ctx, cfnClient, stackName)
 
-	// The waiter will keep checking the stack status until it's either complete or failed
-	err := waiterCfg.Wait(ctx, describeStacksInput, 5*time.Minute) // You can change the timeout value as needed
 	if err != nil {
-		return fmt.Errorf("failed to wait for stack creation to complete: %v", err)
+		return err
 	}
 
-	return nil
+	if stack == nil {
+		return fmt.Errorf("stack %s does not exist", stackName)
+	}
+
+	// Deleted stacks can only be described by ID
+	stackID := aws.ToString(stack.StackId)
+	seen := map[string]bool{}
+	var failure string
+
+	for {
+		events, err := newStackEvents(ctx, cfnClient, stackID, since, seen)
+
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		if err == nil {
+			// Events are returned newest first
+			for i := len(events) - 1; i >= 0; i-- {
+				event := events[i]
+				seen[aws.ToString(event.EventId)] = true
+				reason := aws.ToString(event.ResourceStatusReason)
+
+				logger.Infof("%s %s %s %s", aws.ToString(event.LogicalResourceId), aws.ToString(event.ResourceType), event.ResourceStatus, reason)
+
+				if failure == "" && strings.HasSuffix(string(event.ResourceStatus), "_FAILED") && !strings.Contains(reason, "cancelled") {
+					failure = fmt.Sprintf("%s (%s): %s", aws.ToString(event.LogicalResourceId), aws.ToString(event.ResourceType), reason)
+				}
+			}
+		}
+
+		stack, err = GetCFNStack(ctx, cfnClient, stackID)
+
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		if err == nil && stack != nil && !strings.HasSuffix(string(stack.StackStatus), "_IN_PROGRESS") {
+			switch stack.StackStatus {
+			case types.StackStatusCreateComplete, types.StackStatusUpdateComplete, types.StackStatusDeleteComplete:
+				return nil
+			}
+
+			if failure == "" {
+				failure = aws.ToString(stack.StackStatusReason)
+			}
+
+			return fmt.Errorf("stack %s finished with status %s: %s", stackName, stack.StackStatus, failure)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for stack %s", timeout, stackName)
+		case <-time.After(stackEventPollInterval):
+		}
+	}
+}
+
+// newStackEvents returns the stack events after since that are not in seen, newest first. Events are
+// paged through until an event from before since, or one that was already seen, is reached.
+func newStackEvents(ctx context.Context, cfnClient *cloudformation.Client, stackID string, since time.Time, seen map[string]bool) ([]types.StackEvent, error) {
+	var events []types.StackEvent
+
+	paginator := cloudformation.NewDescribeStackEventsPaginator(cfnClient, &cloudformation.DescribeStackEventsInput{
+		StackName: aws.String(stackID),
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe stack events: %v", err)
+		}
+
+		for _, event := range output.StackEvents {
+			if seen[aws.ToString(event.EventId)] || event.Timestamp == nil || event.Timestamp.Before(since) {
+				return events, nil
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// RecoverCFNStack is the action that gets a failed or stuck stack out of its current status:
+// cancelling or continuing the rollback of an update, or deleting a stack that failed to create.
+// It returns an empty action when the stack needs no recovery.
+func RecoverCFNStack(ctx context.Context, client *cloudformation.Client, stackName string) (string, func() error, error) {
+	stack, err := GetCFNStack(ctx, client, stackName)
+
+	if err != nil || stack == nil {
+		return "", nil, err
+	}
+
+	switch stack.StackStatus {
+	case types.StackStatusUpdateInProgress:
+		return "roll back the update", func() error {
+			_, err := client.CancelUpdateStack(ctx, &cloudformation.CancelUpdateStackInput{StackName: aws.String(stackName)})
+			return err
+		}, nil
+
+	case types.StackStatusUpdateRollbackFailed:
+		return "continue rolling back the update", func() error {
+			_, err := client.ContinueUpdateRollback(ctx, &cloudformation.ContinueUpdateRollbackInput{StackName: aws.String(stackName)})
+			return err
+		}, nil
+
+	case types.StackStatusCreateInProgress, types.StackStatusCreateFailed, types.StackStatusRollbackComplete, types.StackStatusRollbackFailed, types.StackStatusDeleteFailed:
+		return "delete the stack", func() error {
+			return DeleteCFNStack(ctx, client, stackName)
+		}, nil
+	}
+
+	return "", nil, nil
 }
 
 func ValidateCFNStackTemplate(ctx context.Context, client *cloudformation.Client, templateBody string) error {
@@ -62,3 +193,313 @@ func ValidateCFNStackTemplate(ctx context.Context, client *cloudformation.Client
 
 	return nil
 }
+
+// ChangeSet is a change set created for review before it is executed.
+type ChangeSet struct {
+	StackName string        `json:"stackName" yaml:"stackName"`
+	Name      string        `json:"name" yaml:"name"`
+	Type      string        `json:"type" yaml:"type"`
+	Changes   []StackChange `json:"changes" yaml:"changes"`
+}
+
+// StackChange is a single resource change in a change set.
+type StackChange struct {
+	Action       string `json:"action" yaml:"action"`
+	LogicalID    string `json:"logicalId" yaml:"logicalId"`
+	PhysicalID   string `json:"physicalId,omitempty" yaml:"physicalId,omitempty"`
+	ResourceType string `json:"resourceType" yaml:"resourceType"`
+	Replacement  string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+}
+
+// StackStatus is the current state of a stack.
+type StackStatus struct {
+	StackName   string            `json:"stackName" yaml:"stackName"`
+	Status      string            `json:"status" yaml:"status"`
+	Reason      string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	LastUpdated string            `json:"lastUpdated,omitempty" yaml:"lastUpdated,omitempty"`
+	Outputs     map[string]string `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// GetCFNStack returns the stack, or nil if it does not exist.
+func GetCFNStack(ctx context.Context, client *cloudformation.Client, stackName string) (*types.Stack, error) {
+	output, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+
+	if err != nil {
+		var apiErr smithy.APIError
+
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "does not exist") {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to describe stack: %v", err)
+	}
+
+	if len(output.Stacks) == 0 {
+		return nil, nil
+	}
+
+	return &output.Stacks[0], nil
+}
+
+// CreateCFNChangeSet creates a change set that creates the stack, or updates it when it already exists,
+// and waits for it to be ready for review. A change set without changes is deleted and returned empty.
+func CreateCFNChangeSet(ctx context.Context, client *cloudformation.Client, stackName string, templateBody string) (*ChangeSet, error) {
+	stack, err := GetCFNStack(ctx, client, stackName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changeSet := &ChangeSet{
+		StackName: stackName,
+		Name:      fmt.Sprintf("vpc-cidr-manager-%d", time.Now().Unix()),
+		Type:      string(types.ChangeSetTypeUpdate),
+	}
+
+	if stack == nil || stack.StackStatus == types.StackStatusReviewInProgress {
+		changeSet.Type = string(types.ChangeSetTypeCreate)
+	} else if stack.StackStatus == types.StackStatusRollbackComplete {
+		return nil, fmt.Errorf("stack %s is in %s and cannot be updated, destroy it before applying", stackName, stack.StackStatus)
+	}
+
+	_, err = client.CreateChangeSet(ctx, &cloudformation.CreateChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSet.Name),
+		ChangeSetType: types.ChangeSetType(changeSet.Type),
+		TemplateBody:  aws.String(templateBody),
+		Capabilities: []types.Capability{
+			types.CapabilityCapabilityIam,
+			types.CapabilityCapabilityNamedIam,
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create change set: %v", err)
+	}
+
+	describeChangeSetInput := &cloudformation.DescribeChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSet.Name),
+	}
+
+	waitErr := cloudformation.NewChangeSetCreateCompleteWaiter(client).Wait(ctx, describeChangeSetInput, 5*time.Minute)
+
+	for {
+		output, err := client.DescribeChangeSet(ctx, describeChangeSetInput)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe change set: %v", err)
+		}
+
+		if output.Status == types.ChangeSetStatusFailed {
+			reason := aws.ToString(output.StatusReason)
+
+			if strings.Contains(reason, "didn't contain changes") || strings.Contains(reason, "No updates") {
+				return changeSet, DeleteCFNChangeSet(ctx, client, changeSet)
+			}
+
+			return nil, fmt.Errorf("change set %s failed: %s", changeSet.Name, reason)
+		}
+
+		if waitErr != nil {
+			return nil, fmt.Errorf("failed to wait for change set creation to complete: %v", waitErr)
+		}
+
+		for _, change := range output.Changes {
+			if change.ResourceChange == nil {
+				continue
+			}
+
+			changeSet.Changes = append(changeSet.Changes, StackChange{
+				Action:       string(change.ResourceChange.Action),
+				LogicalID:    aws.ToString(change.ResourceChange.LogicalResourceId),
+				PhysicalID:   aws.ToString(change.ResourceChange.PhysicalResourceId),
+				ResourceType: aws.ToString(change.ResourceChange.ResourceType),
+				Replacement:  string(change.ResourceChange.Replacement),
+			})
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+
+		describeChangeSetInput.NextToken = output.NextToken
+	}
+
+	return changeSet, nil
+}
+
+// ExecuteCFNChangeSet starts executing a reviewed change set.
+func ExecuteCFNChangeSet(ctx context.Context, client *cloudformation.Client, changeSet *ChangeSet) error {
+	_, err := client.ExecuteChangeSet(ctx, &cloudformation.ExecuteChangeSetInput{
+		StackName:     aws.String(changeSet.StackName),
+		ChangeSetName: aws.String(changeSet.Name),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to execute change set: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteCFNChangeSet deletes a change set that will not be executed.
+func DeleteCFNChangeSet(ctx context.Context, client *cloudformation.Client, changeSet *ChangeSet) error {
+	_, err := client.DeleteChangeSet(ctx, &cloudformation.DeleteChangeSetInput{
+		StackName:     aws.String(changeSet.StackName),
+		ChangeSetName: aws.String(changeSet.Name),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to delete change set: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteCFNStack starts deleting a stack.
+func DeleteCFNStack(ctx context.Context, client *cloudformation.Client, stackName string) error {
+	_, err := client.DeleteStack(ctx, &cloudformation.DeleteStackInput{
+		StackName: aws.String(stackName),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to delete stack: %v", err)
+	}
+
+	return nil
+}
+
+// GetStackStatuses returns the status of each stack. Stacks that don't exist are reported as NOT_FOUND.
+func GetStackStatuses(ctx context.Context, client *cloudformation.Client, stackNames []string) ([]StackStatus, error) {
+	var statuses []StackStatus
+
+	for _, stackName := range stackNames {
+		stack, err := GetCFNStack(ctx, client, stackName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		status := StackStatus{StackName: stackName, Status: "NOT_FOUND"}
+
+		if stack != nil {
+			status.Status = string(stack.StackStatus)
+			status.Reason = aws.ToString(stack.StackStatusReason)
+			status.Outputs = map[string]string{}
+
+			lastUpdated := stack.LastUpdatedTime
+
+			if lastUpdated == nil {
+				lastUpdated = stack.CreationTime
+			}
+
+			if lastUpdated != nil {
+				status.LastUpdated = lastUpdated.Format(time.RFC3339)
+			}
+
+			for _, output := range stack.Outputs {
+				status.Outputs[aws.ToString(output.OutputKey)] = aws.ToString(output.OutputValue)
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// PrintChangeSet writes the resource changes of a change set to stdout in the requested output format.
+func PrintChangeSet(changeSet *ChangeSet, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(changeSet, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(changeSet)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Action", "Logical ID", "Physical ID", "Resource Type", "Replacement"})
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, change := range changeSet.Changes {
+			table.Append([]string{change.Action, change.LogicalID, change.PhysicalID, change.ResourceType, change.Replacement})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
+
+// PrintStackStatuses writes the stack statuses to stdout in the requested output format.
+func PrintStackStatuses(statuses []StackStatus, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(statuses, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(statuses)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Stack", "Status", "Reason", "Last Updated", "Outputs"})
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, status := range statuses {
+			var outputs []string
+
+			for key, value := range status.Outputs {
+				outputs = append(outputs, key+"="+value)
+			}
+
+			sort.Strings(outputs)
+			table.Append([]string{status.StackName, status.Status, status.Reason, status.LastUpdated, strings.Join(outputs, " ")})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
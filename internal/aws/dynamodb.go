@@ -10,47 +10,285 @@ import (
 	"strings"
 	"time"
 
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/olekukonko/tablewriter"
 	log "github.com/sirupsen/logrus"
 )
 
-func PushToDynamoDB(ctx context.Context, client *dynamodb.Client, vpcInfo VPCInfo, tableName string) error {
+// maxTransactItems is the maximum number of items DynamoDB accepts in a single transaction.
+const maxTransactItems = 100
+
+// Reservation is a CIDR block item stored in the reservations table.
+type Reservation struct {
+	CIDR       string `dynamodbav:"CIDR" json:"cidr" yaml:"cidr"`
+	AccountID  string `dynamodbav:"AccountID,omitempty" json:"accountId,omitempty" yaml:"accountId,omitempty"`
+	VpcID      string `dynamodbav:"VpcId" json:"vpcId,omitempty" yaml:"vpcId,omitempty"`
+	VpcName    string `dynamodbav:"VpcName" json:"vpcName,omitempty" yaml:"vpcName,omitempty"`
+	ReservedAt string `dynamodbav:"ReservedAt" json:"reservedAt,omitempty" yaml:"reservedAt,omitempty"`
+	ReservedBy string `dynamodbav:"ReservedBy" json:"reservedBy,omitempty" yaml:"reservedBy,omitempty"`
+	Status     string `dynamodbav:"Status" json:"status,omitempty" yaml:"status,omitempty"`
+
+	// Source records where a reservation came from when it was not reserved or imported by this tool.
+	Source           string `dynamodbav:"Source,omitempty" json:"source,omitempty" yaml:"source,omitempty"`
+	Description      string `dynamodbav:"Description,omitempty" json:"description,omitempty" yaml:"description,omitempty"`
+	IpamPoolID       string `dynamodbav:"IpamPoolId,omitempty" json:"ipamPoolId,omitempty" yaml:"ipamPoolId,omitempty"`
+	IpamAllocationID string `dynamodbav:"IpamAllocationId,omitempty" json:"ipamAllocationId,omitempty" yaml:"ipamAllocationId,omitempty"`
+
+	// Child reservations, such as subnets, point at the CIDR of the reservation they were carved from.
+	ParentCIDR       string `dynamodbav:"ParentCIDR,omitempty" json:"parentCidr,omitempty" yaml:"parentCidr,omitempty"`
+	Type             string `dynamodbav:"Type,omitempty" json:"type,omitempty" yaml:"type,omitempty"`
+	Tier             string `dynamodbav:"Tier,omitempty" json:"tier,omitempty" yaml:"tier,omitempty"`
+	AvailabilityZone string `dynamodbav:"AvailabilityZone,omitempty" json:"availabilityZone,omitempty" yaml:"availabilityZone,omitempty"`
+	SubnetID         string `dynamodbav:"SubnetId,omitempty" json:"subnetId,omitempty" yaml:"subnetId,omitempty"`
+	SubnetName       string `dynamodbav:"SubnetName,omitempty" json:"subnetName,omitempty" yaml:"subnetName,omitempty"`
+	AvailableIPs     int32  `dynamodbav:"AvailableIpAddressCount,omitempty" json:"availableIpAddressCount,omitempty" yaml:"availableIpAddressCount,omitempty"`
+
+	// Region is the region of the table replica the reservation was written to.
+	Region string `dynamodbav:"Region,omitempty" json:"region,omitempty" yaml:"region,omitempty"`
+}
+
+// IsExternal reports whether the reservation is an on-prem, partner or VPN range. External ranges
+// block allocation like any other reservation, but they don't exist in AWS so they are never
+// compared against live VPCs.
+func (r Reservation) IsExternal() bool {
+	return helpers.IsExternalSource(r.Source)
+}
+
+// IsChild reports whether the reservation was carved out of another reservation.
+// Child reservations never block allocation, their parent already does.
+func (r Reservation) IsChild() bool {
+	return r.ParentCIDR != ""
+}
 
-	itemExists, err := CheckItemExists(ctx, client, vpcInfo.CIDR, tableName)
+// PushToDynamoDB stores an imported VPC CIDR. It returns false without an error when the CIDR was
+// imported for the same VPC before, so a re-import can go on to refresh the VPC's subnets.
+func PushToDynamoDB(ctx context.Context, client *dynamodb.Client, vpcInfo VPCInfo, tableName string) (bool, error) {
+	vpcInfo.Region = writeRegion(client)
+
+	existing, err := importedVpcID(ctx, client, vpcInfo.CIDR, tableName)
 
 	if err != nil {
-		return fmt.Errorf("Got error checking if item exists: %v", err)
+		return false, err
 	}
 
-	if itemExists {
-		return fmt.Errorf("Item already exists in DynamoDB")
+	if existing == vpcInfo.VpcID {
+		return false, nil
+	}
+
+	if existing != "" {
+		return false, fmt.Errorf("CIDR %s is already imported for vpc %s", vpcInfo.CIDR, existing)
 	}
 
 	// Covert VPCInfo to JSON and push to DynamoDB
 	av, err := attributevalue.MarshalMap(vpcInfo)
 
 	if err != nil {
-		return fmt.Errorf("Got error marshalling map: %v", err)
+		return false, fmt.Errorf("Got error marshalling map: %v", err)
 	}
 
 	_, err = client.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(tableName),
-		Item:      av,
+		TableName:           aws.String(tableName),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(CIDR)"),
 	})
 
 	if err != nil {
-		return fmt.Errorf("Got error calling PutItem: %v", err)
+		var conditionFailed *types.ConditionalCheckFailedException
+
+		if errors.As(err, &conditionFailed) {
+			return false, fmt.Errorf("Item already exists in DynamoDB")
+		}
+
+		return false, fmt.Errorf("Got error calling PutItem: %v", err)
+	}
+
+	return true, nil
+}
+
+// importedVpcID returns the VPC ID stored on the item for cidr, or an empty string when there is no
+// item. It fails when the item exists but isn't linked to a VPC.
+func importedVpcID(ctx context.Context, client *dynamodb.Client, cidr string, tableName string) (string, error) {
+	reservation, err := GetReservation(ctx, client, tableName, cidr)
+
+	if err != nil {
+		return "", err
+	}
+
+	if reservation == nil {
+		return "", nil
+	}
+
+	if reservation.VpcID == "" {
+		return "", fmt.Errorf("CIDR %s is reserved but not linked to a VPC, link it with attach-vpc", cidr)
+	}
+
+	return reservation.VpcID, nil
+}
+
+// PushSubnetsToDynamoDB stores the subnets of an imported VPC as child reservations of the VPC's CIDR.
+// Subnets that were imported before are refreshed, items that belong to another reservation are never overwritten.
+func PushSubnetsToDynamoDB(ctx context.Context, client *dynamodb.Client, vpcInfo VPCInfo, subnets []SubnetInfo, tableName string, logger *log.Logger) error {
+	vpcInfo.Region = writeRegion(client)
+
+	for _, subnet := range subnets {
+		if subnet.CIDR == vpcInfo.CIDR {
+			logger.Warnf("Skipping subnet %s, it covers the whole VPC CIDR %s", subnet.SubnetID, vpcInfo.CIDR)
+			continue
+		}
+
+		av, err := attributevalue.MarshalMap(subnetReservation(vpcInfo, subnet))
+
+		if err != nil {
+			return fmt.Errorf("Got error marshalling map: %v", err)
+		}
+
+		logger.Debugf("Importing subnet %s (%s)", subnet.SubnetID, subnet.CIDR)
+		_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(tableName),
+			Item:                av,
+			ConditionExpression: aws.String("attribute_not_exists(CIDR) OR ParentCIDR = :parent"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":parent": &types.AttributeValueMemberS{Value: vpcInfo.CIDR},
+			},
+		})
+
+		if err != nil {
+			var conditionFailed *types.ConditionalCheckFailedException
+
+			if errors.As(err, &conditionFailed) {
+				return fmt.Errorf("subnet %s CIDR %s is already reserved outside of VPC %s", subnet.SubnetID, subnet.CIDR, vpcInfo.VpcID)
+			}
+
+			return fmt.Errorf("Got error calling PutItem: %v", err)
+		}
 	}
 
 	return nil
 }
 
+// ImportReservations writes reservations that were discovered outside of this tool to the table.
+// CIDRs that are already reserved are skipped. Reservations that overlap a reservation in the table,
+// or one written earlier in the same import, are skipped with a warning. Child reservations never
+// block allocation, so they are not checked for overlaps.
+// It returns the number of reservations that were written.
+func ImportReservations(ctx context.Context, client *dynamodb.Client, tableName string, reservations []Reservation, logger *log.Logger) (int, error) {
+	imported := 0
+
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return imported, fmt.Errorf("failed to fetch existing CIDRs: %w", err)
+	}
+
+	for _, reservation := range reservations {
+		if !reservation.IsChild() {
+			_, network, err := net.ParseCIDR(reservation.CIDR)
+
+			if err != nil {
+				return imported, fmt.Errorf("%w", err)
+			}
+
+			if conflicts := importConflicts(network, existingCIDRs); len(conflicts) > 0 {
+				logger.Warnf("CIDR %s overlaps with %s, skipping", reservation.CIDR, strings.Join(conflicts, ", "))
+				continue
+			}
+		}
+
+		if reservation.Region == "" {
+			reservation.Region = writeRegion(client)
+		}
+
+		item, err := attributevalue.MarshalMap(reservation)
+
+		if err != nil {
+			return imported, fmt.Errorf("Got error marshalling map: %v", err)
+		}
+
+		_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(CIDR)"),
+		})
+
+		if err != nil {
+			var conditionFailed *types.ConditionalCheckFailedException
+
+			if errors.As(err, &conditionFailed) {
+				logger.Infof("CIDR %s is already reserved, skipping", reservation.CIDR)
+				continue
+			}
+
+			return imported, fmt.Errorf("Got error calling PutItem: %v", err)
+		}
+
+		logger.Debugf("Imported CIDR %s", reservation.CIDR)
+		imported++
+
+		if !reservation.IsChild() {
+			existingCIDRs = append(existingCIDRs, reservation.CIDR)
+		}
+	}
+
+	return imported, nil
+}
+
+// importConflicts returns the CIDRs in existingCIDRs that overlap cidr. A CIDR equal to cidr is not a
+// conflict, imports skip CIDRs that are already reserved.
+func importConflicts(cidr *net.IPNet, existingCIDRs []string) []string {
+	var conflicts []string
+
+	for _, existingCIDR := range findOverlappingCIDRs(cidr, existingCIDRs) {
+		if existingCIDR != cidr.String() {
+			conflicts = append(conflicts, existingCIDR)
+		}
+	}
+
+	return conflicts
+}
+
+// ExternalRangeReservations converts external ranges into reservations owned by actor.
+func ExternalRangeReservations(ranges []helpers.ExternalRange, actor string) []Reservation {
+	var reservations []Reservation
+
+	for _, externalRange := range ranges {
+		reservations = append(reservations, Reservation{
+			CIDR:        externalRange.CIDR,
+			VpcName:     externalRange.Name,
+			ReservedAt:  time.Now().Format(time.RFC3339),
+			ReservedBy:  actor,
+			Status:      "external",
+			Source:      externalRange.Source,
+			Description: externalRange.Description,
+		})
+	}
+
+	return reservations
+}
+
+// subnetReservation builds the child reservation stored for a subnet of an imported VPC.
+func subnetReservation(vpcInfo VPCInfo, subnet SubnetInfo) Reservation {
+	return Reservation{
+		CIDR:             subnet.CIDR,
+		AccountID:        vpcInfo.AccountID,
+		VpcID:            vpcInfo.VpcID,
+		VpcName:          vpcInfo.VpcName,
+		ReservedAt:       vpcInfo.ReservedAt.Format(time.RFC3339),
+		ReservedBy:       vpcInfo.ReservedBy,
+		Status:           vpcInfo.Status,
+		ParentCIDR:       vpcInfo.CIDR,
+		Type:             reservationTypeSubnet,
+		AvailabilityZone: subnet.AvailabilityZone,
+		SubnetID:         subnet.SubnetID,
+		SubnetName:       subnet.SubnetName,
+		AvailableIPs:     subnet.AvailableIPAddressCount,
+		Region:           vpcInfo.Region,
+	}
+}
+
 func CheckItemExists(ctx context.Context, client *dynamodb.Client, cidr string, tableName string) (bool, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(tableName),
@@ -73,8 +311,19 @@ func CheckItemExists(ctx context.Context, client *dynamodb.Client, cidr string,
 	return true, nil
 }
 
-func GetDynamoDBClient(cfg aws.Config) (*dynamodb.Client, error) {
-	client := dynamodb.NewFromConfig(cfg)
+// writeRegion is the region a client writes to, recorded on reservations so writes to a global
+// table can be traced to the replica they were made in.
+func writeRegion(client *dynamodb.Client) string {
+	return client.Options().Region
+}
+
+// GetDynamoDBClient returns a DynamoDB client, sent to endpoint instead of the AWS endpoint when it is set.
+func GetDynamoDBClient(cfg aws.Config, endpoint string) (*dynamodb.Client, error) {
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
 
 	if client == nil {
 		return nil, errors.New("DynamoDB client is nil")
@@ -96,7 +345,10 @@ func checkTableExists(ctx context.Context, client *dynamodb.Client, name string)
 	return nil
 }
 
-func CreateDynamoDBTable(ctx context.Context, client *dynamodb.Client, name string, logger *log.Logger) error {
+// CreateDynamoDBTable creates the reservations table with the SDK, without a CloudFormation stack.
+// Point-in-time recovery can only be enabled once the table is active, so the table is waited for then.
+// It returns false when the table already exists, the options are not applied to an existing table.
+func CreateDynamoDBTable(ctx context.Context, client *dynamodb.Client, name string, opts helpers.TableOptions, logger *log.Logger) (bool, error) {
 	err := checkTableExists(ctx, client, name)
 
 	if err != nil {
@@ -106,8 +358,7 @@ func CreateDynamoDBTable(ctx context.Context, client *dynamodb.Client, name stri
 		if errors.As(err, &notFound) {
 			logger.Debugf("Table %s does not exist, creating it now.\n", name)
 
-			// Create table
-			_, err = client.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+			input := &dynamodb.CreateTableInput{
 				TableName: aws.String(name),
 				KeySchema: []types.KeySchemaElement{
 					{
@@ -121,59 +372,157 @@ func CreateDynamoDBTable(ctx context.Context, client *dynamodb.Client, name stri
 						AttributeType: types.ScalarAttributeTypeS,
 					},
 				},
-				ProvisionedThroughput: &types.ProvisionedThroughput{
-					ReadCapacityUnits:  aws.Int64(5),
-					WriteCapacityUnits: aws.Int64(5),
-				},
-			})
+				BillingMode:               types.BillingMode(opts.BillingMode),
+				DeletionProtectionEnabled: aws.Bool(opts.DeletionProtection),
+			}
+
+			// Global tables replicate through the table's stream
+			if opts.Global() {
+				input.StreamSpecification = &types.StreamSpecification{
+					StreamEnabled:  aws.Bool(true),
+					StreamViewType: types.StreamViewTypeNewAndOldImages,
+				}
+			}
+
+			if opts.Provisioned() {
+				input.ProvisionedThroughput = &types.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(opts.ReadCapacity),
+					WriteCapacityUnits: aws.Int64(opts.WriteCapacity),
+				}
+			}
+
+			if opts.KMSKeyArn != "" {
+				input.SSESpecification = &types.SSESpecification{
+					Enabled:        aws.Bool(true),
+					SSEType:        types.SSETypeKms,
+					KMSMasterKeyId: aws.String(opts.KMSKeyArn),
+				}
+			}
+
+			for key, value := range opts.Tags {
+				input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+			}
+
+			// Create table
+			_, err = client.CreateTable(ctx, input)
 
 			if err != nil {
-				return fmt.Errorf("%w", err)
+				return false, fmt.Errorf("%w", err)
+			}
+
+			if opts.Global() {
+				if err := createReplicas(ctx, client, name, opts.ReplicaRegions, logger); err != nil {
+					return true, err
+				}
+			}
+
+			if opts.PointInTimeRecovery {
+				regions := opts.ReplicaRegions
+
+				if len(regions) == 0 {
+					regions = []string{writeRegion(client)}
+				}
+
+				// Continuous backups are a setting of each replica, not of the global table
+				for _, region := range regions {
+					regionClient := dynamodb.New(client.Options(), func(o *dynamodb.Options) {
+						o.Region = region
+					})
+
+					if err := enablePointInTimeRecovery(ctx, regionClient, name, logger); err != nil {
+						return true, err
+					}
+				}
 			}
 		} else if errors.As(err, &inUse) {
-			logger.Printf("Table %s is already in use.\n", name)
+			logger.Debugf("Table %s is already in use.\n", name)
+			return false, nil
 		} else {
-			return fmt.Errorf("%w", err)
+			return false, fmt.Errorf("%w", err)
 		}
 	} else {
-		logger.Printf("Table %s already exists.\n", name)
+		logger.Debugf("Table %s already exists.\n", name)
+		return false, nil
 	}
 
-	return nil
+	return true, nil
 }
 
-func ReserveCIDR(ctx context.Context, client *dynamodb.Client, cidr string, vpcID string, vpcName string, logger *log.Logger) error {
-	var sessionName string
-	tableName := os.Getenv("DDB_TABLE_NAME")
+// createReplicas turns a new table into a global table. DynamoDB only accepts one replica change
+// at a time, so the table is waited for before each replica is added.
+func createReplicas(ctx context.Context, client *dynamodb.Client, name string, regions []string, logger *log.Logger) error {
+	for _, region := range regions {
+		if region == writeRegion(client) {
+			continue
+		}
+
+		if err := waitForTableActive(ctx, client, name, logger); err != nil {
+			return err
+		}
 
-	if tableName == "" {
-		return fmt.Errorf("DDB_TABLE_NAME environment variable is not set")
+		logger.Infof("Adding replica of table %s in %s", name, region)
+		_, err := client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName: aws.String(name),
+			ReplicaUpdates: []types.ReplicationGroupUpdate{
+				{
+					Create: &types.CreateReplicationGroupMemberAction{
+						RegionName: aws.String(region),
+					},
+				},
+			},
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to add replica in %s: %v", region, err)
+		}
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	return waitForTableActive(ctx, client, name, logger)
+}
+
+// waitForTableActive waits until a table is no longer being created or updated.
+func waitForTableActive(ctx context.Context, client *dynamodb.Client, name string, logger *log.Logger) error {
+	logger.Debugf("Waiting for table %s to become active", name)
+	err := dynamodb.NewTableExistsWaiter(client).Wait(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(name),
+	}, 30*time.Minute)
 
 	if err != nil {
-		return fmt.Errorf("failed to load SDK config: %w", err)
+		return fmt.Errorf("failed waiting for table %s: %v", name, err)
 	}
 
-	stsClient := sts.NewFromConfig(cfg)
+	return nil
+}
 
-	output, err := stsClient.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+// enablePointInTimeRecovery waits for a new table to become active and turns on continuous backups.
+func enablePointInTimeRecovery(ctx context.Context, client *dynamodb.Client, name string, logger *log.Logger) error {
+	if err := waitForTableActive(ctx, client, name, logger); err != nil {
+		return err
+	}
+
+	logger.Debugf("Enabling point-in-time recovery on table %s", name)
+	_, err := client.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+		TableName: aws.String(name),
+		PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: aws.Bool(true),
+		},
+	})
 
 	if err != nil {
-		return fmt.Errorf("failed to get caller identity: %w", err)
+		return fmt.Errorf("failed to enable point-in-time recovery: %v", err)
 	}
 
-	arnParts := strings.Split(*output.Arn, "/")
+	return nil
+}
 
-	if len(arnParts) > 2 {
-		sessionName = arnParts[2]
-		logger.Debugf("Session name: %s", sessionName)
-	} else {
-		return fmt.Errorf("failed to parse session name from ARN: %s", *output.Arn)
+func ReserveCIDR(ctx context.Context, client *dynamodb.Client, tableName string, cidr string, vpcID string, vpcName string, actor string, logger *log.Logger) error {
+	if tableName == "" {
+		return fmt.Errorf("DDB_TABLE_NAME environment variable is not set")
 	}
 
-	err = checkTableExists(ctx, client, tableName)
+	logger.Debugf("Actor: %s", actor)
+
+	err := checkTableExists(ctx, client, tableName)
 
 	if err != nil {
 		return fmt.Errorf("%w", err)
@@ -194,40 +543,207 @@ func ReserveCIDR(ctx context.Context, client *dynamodb.Client, cidr string, vpcI
 	}
 
 	// Check if the new CIDR overlaps with any existing CIDRs
+	if overlaps := findOverlappingCIDRs(newCIDR, existingCIDRs); len(overlaps) > 0 {
+		return fmt.Errorf("CIDR %s overlaps with existing CIDR %s", cidr, overlaps[0])
+	}
+
+	// Reserve the new CIDR
+	_, err = client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(tableName),
+		Item:                reservationItem(cidr, vpcID, vpcName, actor, writeRegion(client)),
+		ConditionExpression: aws.String("attribute_not_exists(CIDR)"),
+	})
+	if err != nil {
+		var conditionFailed *types.ConditionalCheckFailedException
+
+		if errors.As(err, &conditionFailed) {
+			return fmt.Errorf("CIDR %s is already reserved", cidr)
+		}
+
+		return fmt.Errorf("failed to reserve CIDR: %w", err)
+	}
+
+	return rollBackOverlapping(ctx, client, tableName, []string{cidr}, logger)
+}
+
+// ReserveCIDRs reserves several CIDRs in a single DynamoDB transaction, so either all of them are reserved or none are.
+func ReserveCIDRs(ctx context.Context, client *dynamodb.Client, tableName string, requests []helpers.ReservationRequest, actor string, logger *log.Logger) error {
+	if tableName == "" {
+		return fmt.Errorf("DDB_TABLE_NAME environment variable is not set")
+	}
+
+	if len(requests) > maxTransactItems {
+		return fmt.Errorf("cannot reserve more than %d CIDRs in one request, got %d", maxTransactItems, len(requests))
+	}
+
+	logger.Debugf("Actor: %s", actor)
+
+	err := checkTableExists(ctx, client, tableName)
+
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return fmt.Errorf("failed to fetch existing CIDRs: %w", err)
+	}
+
+	var transactItems []types.TransactWriteItem
+
+	for i, request := range requests {
+		_, newCIDR, err := net.ParseCIDR(request.CIDR)
+
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		// Earlier CIDRs in the batch count as reserved so the batch can't overlap itself
+		if overlaps := findOverlappingCIDRs(newCIDR, existingCIDRs); len(overlaps) > 0 {
+			return fmt.Errorf("CIDR %s overlaps with existing CIDR %s, no CIDRs were reserved", request.CIDR, overlaps[0])
+		}
+
+		existingCIDRs = append(existingCIDRs, request.CIDR)
+
+		logger.Debugf("Adding CIDR %s (%d/%d) to transaction", request.CIDR, i+1, len(requests))
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(tableName),
+				Item:                reservationItem(request.CIDR, request.VpcID, request.VpcName, actor, writeRegion(client)),
+				ConditionExpression: aws.String("attribute_not_exists(CIDR)"),
+			},
+		})
+	}
+
+	_, err = client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	if err != nil {
+		var canceled *types.TransactionCanceledException
+
+		if errors.As(err, &canceled) {
+			var reasons []string
+
+			for i, reason := range canceled.CancellationReasons {
+				if reason.Code != nil && *reason.Code != "None" {
+					reasons = append(reasons, fmt.Sprintf("%s: %s", requests[i].CIDR, *reason.Code))
+				}
+			}
+
+			return fmt.Errorf("reservation transaction was canceled, no CIDRs were reserved: %s", strings.Join(reasons, ", "))
+		}
+
+		return fmt.Errorf("failed to reserve CIDRs: %w", err)
+	}
+
+	cidrs := make([]string, len(requests))
+
+	for i, request := range requests {
+		cidrs[i] = request.CIDR
+	}
+
+	return rollBackOverlapping(ctx, client, tableName, cidrs, logger)
+}
+
+// rollBackOverlapping checks the CIDRs that were just reserved against the table again. The
+// conditional writes only stop two reservations of the same CIDR, so two runs reserving overlapping
+// CIDRs at the same time can both pass the overlap check before either writes. Each run checks again
+// once its write is done and deletes its own CIDRs when they overlap a CIDR written in the meantime.
+// The check reads consistently, so at most one of two overlapping reservations survives.
+func rollBackOverlapping(ctx context.Context, client *dynamodb.Client, tableName string, cidrs []string, logger *log.Logger) error {
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return fmt.Errorf("failed to check the reserved CIDRs for concurrent reservations: %w", err)
+	}
+
+	var others []string
 
 	for _, existingCIDR := range existingCIDRs {
-		_, existingCIDRBlock, err := net.ParseCIDR(existingCIDR)
+		if !containsString(cidrs, existingCIDR) {
+			others = append(others, existingCIDR)
+		}
+	}
+
+	var conflict string
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
 
 		if err != nil {
-			continue
+			return fmt.Errorf("%w", err)
 		}
 
-		if cidrOverlaps(existingCIDRBlock, newCIDR) {
-			return fmt.Errorf("CIDR %s overlaps with existing CIDR %s", cidr, existingCIDR)
+		if overlaps := findOverlappingCIDRs(network, others); len(overlaps) > 0 {
+			conflict = fmt.Sprintf("CIDR %s overlaps with CIDR %s that was reserved at the same time", cidr, overlaps[0])
+			break
 		}
 	}
 
-	// Reserve the new CIDR
-	_, err = client.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(tableName),
-		Item: map[string]types.AttributeValue{
-			"CIDR":       &types.AttributeValueMemberS{Value: cidr},
-			"VpcId":      &types.AttributeValueMemberS{Value: vpcID},
-			"VpcName":    &types.AttributeValueMemberS{Value: vpcName},
-			"ReservedAt": &types.AttributeValueMemberS{Value: fmt.Sprintf("%v", time.Now())},
-			"ReservedBy": &types.AttributeValueMemberS{Value: sessionName},
-			"Status":     &types.AttributeValueMemberS{Value: "reserved"},
-		},
+	if conflict == "" {
+		return nil
+	}
+
+	logger.Debugf("%s, rolling back", conflict)
+	var transactItems []types.TransactWriteItem
+
+	for _, cidr := range cidrs {
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName: aws.String(tableName),
+				Key: map[string]types.AttributeValue{
+					"CIDR": &types.AttributeValueMemberS{Value: cidr},
+				},
+			},
+		})
+	}
+
+	_, err = client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
 	})
+
 	if err != nil {
-		return fmt.Errorf("failed to reserve CIDR: %w", err)
+		return fmt.Errorf("%s and rolling back failed, release %s: %w", conflict, strings.Join(cidrs, ", "), err)
 	}
-	return nil
+
+	return fmt.Errorf("%s, no CIDRs were reserved, try again", conflict)
 }
 
-func ReleaseCidr(ctx context.Context, client *dynamodb.Client, cidr []string, logger *log.Logger) error {
-	tableName := os.Getenv("DDB_TABLE_NAME")
+// reservationItem builds the DynamoDB item written by ReserveCIDR.
+func reservationItem(cidr string, vpcID string, vpcName string, actor string, region string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"Region":     &types.AttributeValueMemberS{Value: region},
+		"CIDR":       &types.AttributeValueMemberS{Value: cidr},
+		"VpcId":      &types.AttributeValueMemberS{Value: vpcID},
+		"VpcName":    &types.AttributeValueMemberS{Value: vpcName},
+		"ReservedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		"ReservedBy": &types.AttributeValueMemberS{Value: actor},
+		"Status":     &types.AttributeValueMemberS{Value: "reserved"},
+	}
+}
+
+// findOverlappingCIDRs returns the existing CIDRs that overlap with cidr.
+func findOverlappingCIDRs(cidr *net.IPNet, existingCIDRs []string) []string {
+	var overlaps []string
+
+	for _, existingCIDR := range existingCIDRs {
+		_, existingCIDRBlock, err := net.ParseCIDR(existingCIDR)
 
+		if err != nil {
+			continue
+		}
+
+		if cidrOverlaps(existingCIDRBlock, cidr) {
+			overlaps = append(overlaps, existingCIDR)
+		}
+	}
+
+	return overlaps
+}
+
+func ReleaseCidr(ctx context.Context, client *dynamodb.Client, tableName string, cidr []string, logger *log.Logger) error {
 	if tableName == "" {
 		return fmt.Errorf("DDB_TABLE_NAME environment variable is not set")
 	}
@@ -239,7 +755,27 @@ func ReleaseCidr(ctx context.Context, client *dynamodb.Client, cidr []string, lo
 	}
 
 	for _, c := range cidr {
-		_, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		// Release the child reservations first so none are left without a parent
+		children, err := FetchChildReservations(ctx, client, tableName, c)
+
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			logger.Debugf("Releasing child CIDR %s of %s", child.CIDR, c)
+			_, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(tableName),
+				Key: map[string]types.AttributeValue{
+					"CIDR": &types.AttributeValueMemberS{Value: child.CIDR},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to delete child CIDR: %w", err)
+			}
+		}
+
+		_, err = client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
 			TableName: aws.String(tableName),
 			Key: map[string]types.AttributeValue{
 				"CIDR": &types.AttributeValueMemberS{Value: c},
@@ -253,28 +789,99 @@ func ReleaseCidr(ctx context.Context, client *dynamodb.Client, cidr []string, lo
 	return nil
 }
 
-// fetchExistingCIDRs retrieves all reserved CIDRs from the DynamoDB table
+// fetchExistingCIDRs retrieves all reserved CIDRs from the DynamoDB table, leaving out child reservations
 func FetchExistingCIDRs(client *dynamodb.Client, tableName string) ([]string, error) {
-	output, err := client.Scan(context.TODO(), &dynamodb.ScanInput{
+	// Overlap checks must see reservations written just before them
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
 		TableName:            aws.String(tableName),
-		ProjectionExpression: aws.String("CIDR"),
+		ProjectionExpression: aws.String("CIDR, ParentCIDR"),
+		ConsistentRead:       aws.Bool(true),
 	})
-	if err != nil {
-		return nil, err
-	}
 
 	var cidrs []string
-	for _, item := range output.Items {
-		if cidrAttr, ok := item["CIDR"].(*types.AttributeValueMemberS); ok {
-			cidrs = append(cidrs, cidrAttr.Value)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range output.Items {
+			if _, isChild := item["ParentCIDR"]; isChild {
+				continue
+			}
+			if cidrAttr, ok := item["CIDR"].(*types.AttributeValueMemberS); ok {
+				cidrs = append(cidrs, cidrAttr.Value)
+			}
 		}
 	}
 	return cidrs, nil
 }
 
+// FetchChildReservations retrieves the reservations that were carved out of parentCIDR
+func FetchChildReservations(ctx context.Context, client *dynamodb.Client, tableName string, parentCIDR string) ([]Reservation, error) {
+	var children []Reservation
+
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("ParentCIDR = :parent"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":parent": &types.AttributeValueMemberS{Value: parentCIDR},
+		},
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+
+		var page []Reservation
+
+		err = attributevalue.UnmarshalListOfMaps(output.Items, &page)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reservations: %w", err)
+		}
+
+		children = append(children, page...)
+	}
+
+	return children, nil
+}
+
+// FetchReservations retrieves every reservation item from the DynamoDB table
+func FetchReservations(ctx context.Context, client *dynamodb.Client, tableName string) ([]Reservation, error) {
+	var reservations []Reservation
+
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+
+		var page []Reservation
+
+		err = attributevalue.UnmarshalListOfMaps(output.Items, &page)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reservations: %w", err)
+		}
+
+		reservations = append(reservations, page...)
+	}
+
+	return reservations, nil
+}
+
 // cidrOverlaps checks if two CIDRs overlap
 func cidrOverlaps(cidr1, cidr2 *net.IPNet) bool {
-	return cidr1.Contains(cidr2.IP) || cidr2.Contains(cidr1.IP)
+	return helpers.CIDRsOverlap(cidr1, cidr2)
 }
 
 func ListCIDRs(ctx context.Context, client *dynamodb.Client, tableName string, outputFormat string) error {
@@ -6,19 +6,18 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go/aws"
 )
 
-func GetIAMClient() (*iam.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-
-	if err != nil {
-		return nil, fmt.Errorf("%v", err)
-	}
-
-	client := iam.NewFromConfig(cfg)
+// GetIAMClient returns an IAM client, sent to endpoint instead of the AWS endpoint when it is set.
+func GetIAMClient(cfg awsv2.Config, endpoint string) (*iam.Client, error) {
+	client := iam.NewFromConfig(cfg, func(o *iam.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = awsv2.String(endpoint)
+		}
+	})
 
 	return client, nil
 }
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Kinds of identity that can run the CLI.
+const (
+	IdentityRoot          = "root"
+	IdentityUser          = "user"
+	IdentityAssumedRole   = "assumed-role"
+	IdentitySSO           = "sso"
+	IdentityFederatedUser = "federated-user"
+)
+
+// ssoRolePrefix starts the name of every role IAM Identity Center creates in member accounts.
+const ssoRolePrefix = "AWSReservedSSO_"
+
+// Identity is the principal running the CLI, as returned by GetCallerIdentity.
+type Identity struct {
+	AccountID string
+	Arn       string
+	Type      string
+
+	// Role is the name of the assumed role, empty for users.
+	Role string
+
+	// Name is the user name, role session name or federated user name, used as the actor of reservations.
+	Name string
+}
+
+// ResolveIdentity looks up the caller identity with STS.
+func ResolveIdentity(ctx context.Context, client *sts.Client) (Identity, error) {
+	output, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	identity, err := ParseIdentityArn(aws.ToString(output.Arn))
+
+	if err != nil {
+		return Identity{}, err
+	}
+
+	identity.AccountID = aws.ToString(output.Account)
+
+	return identity, nil
+}
+
+// ParseIdentityArn parses the ARN of an IAM user, root user, assumed role, SSO role session or federated user.
+// User ARNs may include a path, such as arn:aws:iam::123456789012:user/admins/alice.
+func ParseIdentityArn(arn string) (Identity, error) {
+	parts := strings.SplitN(arn, ":", 6)
+
+	if len(parts) != 6 || parts[0] != "arn" {
+		return Identity{}, fmt.Errorf("invalid identity ARN: %s", arn)
+	}
+
+	identity := Identity{AccountID: parts[4], Arn: arn}
+	resource := strings.Split(parts[5], "/")
+
+	switch {
+	case resource[0] == "root":
+		identity.Type = IdentityRoot
+		identity.Name = "root"
+
+	case resource[0] == "user" && len(resource) >= 2:
+		identity.Type = IdentityUser
+		identity.Name = resource[len(resource)-1]
+
+	case resource[0] == "assumed-role" && len(resource) >= 3:
+		identity.Type = IdentityAssumedRole
+		identity.Role = resource[1]
+		identity.Name = strings.Join(resource[2:], "/")
+
+		if strings.HasPrefix(identity.Role, ssoRolePrefix) {
+			identity.Type = IdentitySSO
+		}
+
+	case resource[0] == "federated-user" && len(resource) >= 2:
+		identity.Type = IdentityFederatedUser
+		identity.Name = strings.Join(resource[1:], "/")
+
+	default:
+		return Identity{}, fmt.Errorf("unsupported identity ARN: %s", arn)
+	}
+
+	if identity.AccountID == "" || identity.Name == "" {
+		return Identity{}, fmt.Errorf("invalid identity ARN: %s", arn)
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,126 @@
+package aws
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIdentityArn(t *testing.T) {
+	tests := []struct {
+		name     string
+		arn      string
+		identity Identity
+		err      string
+	}{
+		{
+			name: "root user",
+			arn:  "arn:aws:iam::123456789012:root",
+			identity: Identity{
+				AccountID: "123456789012",
+				Arn:       "arn:aws:iam::123456789012:root",
+				Type:      IdentityRoot,
+				Name:      "root",
+			},
+		},
+		{
+			name: "user with a path",
+			arn:  "arn:aws:iam::123456789012:user/admins/alice",
+			identity: Identity{
+				AccountID: "123456789012",
+				Arn:       "arn:aws:iam::123456789012:user/admins/alice",
+				Type:      IdentityUser,
+				Name:      "alice",
+			},
+		},
+		{
+			name: "assumed role",
+			arn:  "arn:aws-us-gov:sts::123456789012:assumed-role/deploy/ci-run",
+			identity: Identity{
+				AccountID: "123456789012",
+				Arn:       "arn:aws-us-gov:sts::123456789012:assumed-role/deploy/ci-run",
+				Type:      IdentityAssumedRole,
+				Role:      "deploy",
+				Name:      "ci-run",
+			},
+		},
+		{
+			name: "SSO role session",
+			arn:  "arn:aws:sts::123456789012:assumed-role/AWSReservedSSO_Admin_0123456789abcdef/alice@example.com",
+			identity: Identity{
+				AccountID: "123456789012",
+				Arn:       "arn:aws:sts::123456789012:assumed-role/AWSReservedSSO_Admin_0123456789abcdef/alice@example.com",
+				Type:      IdentitySSO,
+				Role:      "AWSReservedSSO_Admin_0123456789abcdef",
+				Name:      "alice@example.com",
+			},
+		},
+		{
+			name: "federated user",
+			arn:  "arn:aws:sts::123456789012:federated-user/bob",
+			identity: Identity{
+				AccountID: "123456789012",
+				Arn:       "arn:aws:sts::123456789012:federated-user/bob",
+				Type:      IdentityFederatedUser,
+				Name:      "bob",
+			},
+		},
+		{
+			name: "empty",
+			arn:  "",
+			err:  "invalid identity ARN",
+		},
+		{
+			name: "too few fields",
+			arn:  "arn:aws:iam::123456789012",
+			err:  "invalid identity ARN",
+		},
+		{
+			name: "not an ARN",
+			arn:  "urn:aws:iam::123456789012:user/alice",
+			err:  "invalid identity ARN",
+		},
+		{
+			name: "missing account",
+			arn:  "arn:aws:iam:::user/alice",
+			err:  "invalid identity ARN",
+		},
+		{
+			name: "user without a name",
+			arn:  "arn:aws:iam::123456789012:user/",
+			err:  "invalid identity ARN",
+		},
+		{
+			name: "assumed role without a session",
+			arn:  "arn:aws:sts::123456789012:assumed-role/deploy",
+			err:  "unsupported identity ARN",
+		},
+		{
+			name: "role",
+			arn:  "arn:aws:iam::123456789012:role/deploy",
+			err:  "unsupported identity ARN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := ParseIdentityArn(tt.arn)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(identity, tt.identity) {
+				t.Errorf("identity %+v, want %+v", identity, tt.identity)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// SourceIPAM marks reservations that were imported from Amazon VPC IPAM.
+const SourceIPAM = "ipam"
+
+// IpamPool is an IPAM pool together with the CIDRs provisioned to it.
+type IpamPool struct {
+	PoolID      string
+	Description string
+	Locale      string
+	CIDRs       []string
+}
+
+// GetIpamPools returns the IPv4 IPAM pools with the given IDs, or every IPv4 pool when poolIDs is empty.
+func GetIpamPools(ctx context.Context, client *ec2.Client, poolIDs []string) ([]IpamPool, error) {
+	var pools []IpamPool
+
+	paginator := ec2.NewDescribeIpamPoolsPaginator(client, &ec2.DescribeIpamPoolsInput{
+		IpamPoolIds: poolIDs,
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe IPAM pools: %v", err)
+		}
+
+		for _, pool := range output.IpamPools {
+			if pool.AddressFamily != types.AddressFamilyIpv4 {
+				continue
+			}
+
+			ipamPool := IpamPool{
+				PoolID:      aws.ToString(pool.IpamPoolId),
+				Description: aws.ToString(pool.Description),
+				Locale:      aws.ToString(pool.Locale),
+			}
+
+			cidrPaginator := ec2.NewGetIpamPoolCidrsPaginator(client, &ec2.GetIpamPoolCidrsInput{
+				IpamPoolId: pool.IpamPoolId,
+			})
+
+			for cidrPaginator.HasMorePages() {
+				cidrOutput, err := cidrPaginator.NextPage(ctx)
+
+				if err != nil {
+					return nil, fmt.Errorf("failed to get CIDRs of IPAM pool %s: %v", ipamPool.PoolID, err)
+				}
+
+				for _, poolCidr := range cidrOutput.IpamPoolCidrs {
+					if poolCidr.State == types.IpamPoolCidrStateProvisioned {
+						ipamPool.CIDRs = append(ipamPool.CIDRs, aws.ToString(poolCidr.Cidr))
+					}
+				}
+			}
+
+			pools = append(pools, ipamPool)
+		}
+	}
+
+	return pools, nil
+}
+
+// GetIpamPoolReservations converts the allocations of an IPAM pool into reservations.
+// Allocations to child pools are left out, they are address space and not reservations.
+func GetIpamPoolReservations(ctx context.Context, client *ec2.Client, pool IpamPool) ([]Reservation, error) {
+	var reservations []Reservation
+
+	paginator := ec2.NewGetIpamPoolAllocationsPaginator(client, &ec2.GetIpamPoolAllocationsInput{
+		IpamPoolId: aws.String(pool.PoolID),
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to get allocations of IPAM pool %s: %v", pool.PoolID, err)
+		}
+
+		for _, allocation := range output.IpamPoolAllocations {
+			if allocation.ResourceType == types.IpamPoolAllocationResourceTypeIpamPool {
+				continue
+			}
+
+			reservation := Reservation{
+				CIDR:             aws.ToString(allocation.Cidr),
+				AccountID:        aws.ToString(allocation.ResourceOwner),
+				VpcName:          aws.ToString(allocation.Description),
+				ReservedAt:       time.Now().Format(time.RFC3339),
+				ReservedBy:       SourceIPAM,
+				Status:           "reserved",
+				Source:           SourceIPAM,
+				IpamPoolID:       pool.PoolID,
+				IpamAllocationID: aws.ToString(allocation.IpamPoolAllocationId),
+			}
+
+			if allocation.ResourceType == types.IpamPoolAllocationResourceTypeVpc {
+				reservation.VpcID = aws.ToString(allocation.ResourceId)
+				reservation.Status = "in-use"
+			}
+
+			reservations = append(reservations, reservation)
+		}
+	}
+
+	return reservations, nil
+}
+
+// GetIpamDiscoveredReservations converts the VPCs found by an IPAM resource discovery into reservations.
+func GetIpamDiscoveredReservations(ctx context.Context, client *ec2.Client, resourceDiscoveryID string, resourceRegion string) ([]Reservation, error) {
+	var reservations []Reservation
+
+	paginator := ec2.NewGetIpamDiscoveredResourceCidrsPaginator(client, &ec2.GetIpamDiscoveredResourceCidrsInput{
+		IpamResourceDiscoveryId: aws.String(resourceDiscoveryID),
+		ResourceRegion:          aws.String(resourceRegion),
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("resource-type"),
+				Values: []string{string(types.IpamResourceTypeVpc)},
+			},
+		},
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to get discovered resource CIDRs of %s: %v", resourceDiscoveryID, err)
+		}
+
+		for _, resource := range output.IpamDiscoveredResourceCidrs {
+			_, network, err := net.ParseCIDR(aws.ToString(resource.ResourceCidr))
+
+			// Skip IPv6 CIDRs, reservations are IPv4 only
+			if err != nil || network.IP.To4() == nil {
+				continue
+			}
+
+			reservation := Reservation{
+				CIDR:       network.String(),
+				AccountID:  aws.ToString(resource.ResourceOwnerId),
+				VpcID:      aws.ToString(resource.ResourceId),
+				ReservedAt: time.Now().Format(time.RFC3339),
+				ReservedBy: SourceIPAM,
+				Status:     "in-use",
+				Source:     SourceIPAM,
+			}
+
+			for _, tag := range resource.ResourceTags {
+				if aws.ToString(tag.Key) == "Name" {
+					reservation.VpcName = aws.ToString(tag.Value)
+				}
+			}
+
+			reservations = append(reservations, reservation)
+		}
+	}
+
+	return reservations, nil
+}
+
+// ExportToIpam allocates every top level reservation that falls inside the pool as an IPAM pool allocation,
+// and records the allocation ID on the reservation. With dryRun set, EC2 only checks the allocations are allowed.
+// It returns the CIDRs that were allocated.
+func ExportToIpam(ctx context.Context, ec2Client *ec2.Client, dynamoClient *dynamodb.Client, tableName string, pool IpamPool, dryRun bool, logger *log.Logger) ([]string, error) {
+	reservations, err := FetchReservations(ctx, dynamoClient, tableName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var allocated []string
+
+	for _, reservation := range reservations {
+		if reservation.IsChild() || reservation.Source == SourceIPAM || reservation.IpamAllocationID != "" {
+			continue
+		}
+
+		if !insideAny(reservation.CIDR, pool.CIDRs) {
+			logger.Debugf("CIDR %s is outside IPAM pool %s, skipping", reservation.CIDR, pool.PoolID)
+			continue
+		}
+
+		description := reservation.VpcName
+
+		if description == "" {
+			description = reservation.VpcID
+		}
+
+		// The client token makes retries of the same export idempotent
+		output, err := ec2Client.AllocateIpamPoolCidr(ctx, &ec2.AllocateIpamPoolCidrInput{
+			IpamPoolId:  aws.String(pool.PoolID),
+			Cidr:        aws.String(reservation.CIDR),
+			Description: aws.String("vpc-cidr-manager: " + description),
+			ClientToken: aws.String(fmt.Sprintf("%x", sha256.Sum256([]byte(pool.PoolID+reservation.CIDR)))[:32]),
+			DryRun:      aws.Bool(dryRun),
+		})
+
+		if dryRun {
+			var apiErr smithy.APIError
+
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "DryRunOperation" {
+				allocated = append(allocated, reservation.CIDR)
+				continue
+			}
+		}
+
+		if err != nil {
+			return allocated, fmt.Errorf("failed to allocate CIDR %s in IPAM pool %s: %v", reservation.CIDR, pool.PoolID, err)
+		}
+
+		allocationID := aws.ToString(output.IpamPoolAllocation.IpamPoolAllocationId)
+
+		_, err = dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(tableName),
+			Key: map[string]dynamodbTypes.AttributeValue{
+				"CIDR": &dynamodbTypes.AttributeValueMemberS{Value: reservation.CIDR},
+			},
+			UpdateExpression: aws.String("SET IpamPoolId = :pool, IpamAllocationId = :allocation"),
+			ExpressionAttributeValues: map[string]dynamodbTypes.AttributeValue{
+				":pool":       &dynamodbTypes.AttributeValueMemberS{Value: pool.PoolID},
+				":allocation": &dynamodbTypes.AttributeValueMemberS{Value: allocationID},
+			},
+		})
+
+		if err != nil {
+			return allocated, fmt.Errorf("CIDR %s was allocated as %s but the reservation could not be updated: %w", reservation.CIDR, allocationID, err)
+		}
+
+		logger.Debugf("Allocated CIDR %s in IPAM pool %s as %s", reservation.CIDR, pool.PoolID, allocationID)
+		allocated = append(allocated, reservation.CIDR)
+	}
+
+	return allocated, nil
+}
+
+// IpamPoolsAsPools converts IPAM pools into pools that can be written to the config file.
+func IpamPoolsAsPools(ipamPools []IpamPool) []helpers.Pool {
+	var pools []helpers.Pool
+
+	for _, ipamPool := range ipamPools {
+		for _, cidr := range ipamPool.CIDRs {
+			pools = append(pools, helpers.Pool{Name: ipamPool.PoolID, CIDR: cidr})
+		}
+	}
+
+	return pools
+}
+
+// insideAny checks if cidr is fully contained in any of the given CIDRs.
+func insideAny(cidr string, supernets []string) bool {
+	_, network, err := net.ParseCIDR(cidr)
+
+	if err != nil {
+		return false
+	}
+
+	for _, supernet := range supernets {
+		_, supernetNetwork, err := net.ParseCIDR(supernet)
+
+		if err == nil && helpers.ContainsCIDR(supernetNetwork, network) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,384 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	PlanActionPut    = "put"
+	PlanActionUpdate = "update"
+	PlanActionDelete = "delete"
+)
+
+// Plan describes the changes a command would make to the DynamoDB table when run without --dry-run.
+type Plan struct {
+	Command   string          `json:"command" yaml:"command"`
+	TableName string          `json:"tableName" yaml:"tableName"`
+	Changes   []PlannedChange `json:"changes" yaml:"changes"`
+}
+
+// PlannedChange is a single item that would be written to or deleted from the table.
+type PlannedChange struct {
+	Action    string            `json:"action" yaml:"action"`
+	CIDR      string            `json:"cidr" yaml:"cidr"`
+	Exists    bool              `json:"exists" yaml:"exists"`
+	Blocked   bool              `json:"blocked" yaml:"blocked"`
+	Reason    string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Conflicts []string          `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+	Item      map[string]string `json:"item,omitempty" yaml:"item,omitempty"`
+}
+
+// Blocked reports whether any change in the plan would make the real command fail.
+func (p Plan) Blocked() bool {
+	for _, change := range p.Changes {
+		if change.Blocked {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PlanReserveCIDR computes the item ReserveCIDR would write, without writing it.
+func PlanReserveCIDR(ctx context.Context, client *dynamodb.Client, tableName string, cidr string, vpcID string, vpcName string, actor string) (PlannedChange, error) {
+	changes, err := PlanReserveCIDRs(ctx, client, tableName, []helpers.ReservationRequest{
+		{CIDR: cidr, VpcID: vpcID, VpcName: vpcName},
+	}, actor)
+
+	if err != nil {
+		return PlannedChange{}, err
+	}
+
+	return changes[0], nil
+}
+
+// PlanReserveCIDRs computes the items ReserveCIDRs would write, without writing them.
+// CIDRs earlier in the batch are treated as reserved when checking later ones for overlaps.
+func PlanReserveCIDRs(ctx context.Context, client *dynamodb.Client, tableName string, requests []helpers.ReservationRequest, actor string) ([]PlannedChange, error) {
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing CIDRs: %w", err)
+	}
+
+	var changes []PlannedChange
+
+	for _, request := range requests {
+		_, newCIDR, err := net.ParseCIDR(request.CIDR)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		change := PlannedChange{
+			Action:    PlanActionPut,
+			CIDR:      request.CIDR,
+			Item:      flattenItem(reservationItem(request.CIDR, request.VpcID, request.VpcName, actor, writeRegion(client))),
+			Conflicts: findOverlappingCIDRs(newCIDR, existingCIDRs),
+		}
+
+		change.Exists, err = CheckItemExists(ctx, client, request.CIDR, tableName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(change.Conflicts) > 0 {
+			change.Blocked = true
+			change.Reason = fmt.Sprintf("CIDR %s overlaps with existing CIDR %s", request.CIDR, strings.Join(change.Conflicts, ", "))
+		}
+
+		existingCIDRs = append(existingCIDRs, request.CIDR)
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// PlanImportCIDR computes the item PushToDynamoDB would write for vpcInfo, without writing it.
+func PlanImportCIDR(ctx context.Context, client *dynamodb.Client, tableName string, vpcInfo VPCInfo) (PlannedChange, error) {
+	vpcInfo.Region = writeRegion(client)
+
+	change := PlannedChange{
+		Action: PlanActionPut,
+		CIDR:   vpcInfo.CIDR,
+	}
+
+	av, err := attributevalue.MarshalMap(vpcInfo)
+
+	if err != nil {
+		return PlannedChange{}, fmt.Errorf("Got error marshalling map: %v", err)
+	}
+
+	change.Item = flattenItem(av)
+
+	_, newCIDR, err := net.ParseCIDR(vpcInfo.CIDR)
+
+	if err != nil {
+		return PlannedChange{}, fmt.Errorf("%w", err)
+	}
+
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return PlannedChange{}, fmt.Errorf("failed to fetch existing CIDRs: %w", err)
+	}
+
+	change.Conflicts = findOverlappingCIDRs(newCIDR, existingCIDRs)
+
+	change.Exists, err = CheckItemExists(ctx, client, vpcInfo.CIDR, tableName)
+
+	if err != nil {
+		return PlannedChange{}, err
+	}
+
+	if change.Exists {
+		change.Blocked = true
+		change.Reason = "Item already exists in DynamoDB"
+	}
+
+	return change, nil
+}
+
+// PlanImportSubnets computes the child items PushSubnetsToDynamoDB would write for the subnets of vpcInfo, without writing them.
+func PlanImportSubnets(ctx context.Context, client *dynamodb.Client, tableName string, vpcInfo VPCInfo, subnets []SubnetInfo) ([]PlannedChange, error) {
+	vpcInfo.Region = writeRegion(client)
+
+	var changes []PlannedChange
+
+	for _, subnet := range subnets {
+		if subnet.CIDR == vpcInfo.CIDR {
+			continue
+		}
+
+		av, err := attributevalue.MarshalMap(subnetReservation(vpcInfo, subnet))
+
+		if err != nil {
+			return nil, fmt.Errorf("Got error marshalling map: %v", err)
+		}
+
+		output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key: map[string]types.AttributeValue{
+				"CIDR": &types.AttributeValueMemberS{Value: subnet.CIDR},
+			},
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("Got error calling GetItem: %v", err)
+		}
+
+		change := PlannedChange{
+			Action: PlanActionPut,
+			CIDR:   subnet.CIDR,
+			Exists: output.Item != nil,
+			Item:   flattenItem(av),
+		}
+
+		if change.Exists && flattenItem(output.Item)["ParentCIDR"] != vpcInfo.CIDR {
+			change.Blocked = true
+			change.Reason = fmt.Sprintf("subnet %s CIDR is already reserved outside of VPC %s", subnet.SubnetID, vpcInfo.VpcID)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// PlanImportReservations computes which reservations ImportReservations would write, without writing them.
+// Reservations whose CIDR is already in the table are skipped by the import, so they are not blocking.
+// Reservations that overlap the table or an earlier reservation of the import are blocked.
+func PlanImportReservations(ctx context.Context, client *dynamodb.Client, tableName string, reservations []Reservation) ([]PlannedChange, error) {
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing CIDRs: %w", err)
+	}
+
+	var changes []PlannedChange
+
+	for _, reservation := range reservations {
+		_, network, err := net.ParseCIDR(reservation.CIDR)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		item, err := attributevalue.MarshalMap(reservation)
+
+		if err != nil {
+			return nil, fmt.Errorf("Got error marshalling map: %v", err)
+		}
+
+		change := PlannedChange{
+			Action: PlanActionPut,
+			CIDR:   reservation.CIDR,
+			Item:   flattenItem(item),
+		}
+
+		if !reservation.IsChild() {
+			change.Conflicts = importConflicts(network, existingCIDRs)
+		}
+
+		change.Exists, err = CheckItemExists(ctx, client, reservation.CIDR, tableName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case len(change.Conflicts) > 0:
+			change.Blocked = true
+			change.Reason = fmt.Sprintf("CIDR %s overlaps with existing CIDR %s", reservation.CIDR, strings.Join(change.Conflicts, ", "))
+		case change.Exists:
+			change.Reason = "CIDR is already reserved, it will be skipped"
+		case containsString(existingCIDRs, reservation.CIDR):
+			change.Reason = "CIDR is imported earlier in the same import, it will be skipped"
+		default:
+			if !reservation.IsChild() {
+				existingCIDRs = append(existingCIDRs, reservation.CIDR)
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PlanReleaseCIDR computes the items ReleaseCidr would delete, without deleting them.
+func PlanReleaseCIDR(ctx context.Context, client *dynamodb.Client, tableName string, cidrs []string) ([]PlannedChange, error) {
+	var changes []PlannedChange
+
+	for _, c := range cidrs {
+		output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(tableName),
+			Key: map[string]types.AttributeValue{
+				"CIDR": &types.AttributeValueMemberS{Value: c},
+			},
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("Got error calling GetItem: %v", err)
+		}
+
+		change := PlannedChange{
+			Action: PlanActionDelete,
+			CIDR:   c,
+			Exists: output.Item != nil,
+		}
+
+		if change.Exists {
+			change.Item = flattenItem(output.Item)
+		} else {
+			change.Reason = "CIDR is not reserved, delete is a no-op"
+		}
+
+		children, err := FetchChildReservations(ctx, client, tableName, c)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, child := range children {
+			changes = append(changes, PlannedChange{
+				Action: PlanActionDelete,
+				CIDR:   child.CIDR,
+				Exists: true,
+				Reason: fmt.Sprintf("child of %s", c),
+			})
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// PrintPlan writes the plan to stdout in the requested output format.
+func PrintPlan(plan Plan, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(plan, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(plan)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Action", "CIDR", "Exists", "Blocked", "Conflicts", "Reason"})
+
+		for _, change := range plan.Changes {
+			table.Append([]string{
+				change.Action,
+				change.CIDR,
+				strconv.FormatBool(change.Exists),
+				strconv.FormatBool(change.Blocked),
+				strings.Join(change.Conflicts, ", "),
+				change.Reason,
+			})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
+
+// flattenItem converts a DynamoDB item into plain strings so it can be printed as part of a plan.
+func flattenItem(item map[string]types.AttributeValue) map[string]string {
+	flat := make(map[string]string, len(item))
+
+	for key, value := range item {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			flat[key] = v.Value
+		case *types.AttributeValueMemberN:
+			flat[key] = v.Value
+		case *types.AttributeValueMemberBOOL:
+			flat[key] = strconv.FormatBool(v.Value)
+		}
+	}
+
+	return flat
+}
@@ -0,0 +1,34 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"gopkg.in/yaml.v2"
+)
+
+// PrintPolicyDocument writes an IAM policy to stdout. Policies are documents, so every output format
+// other than yaml prints JSON that can be pasted into IAM as is.
+func PrintPolicyDocument(policy helpers.PolicyDocument, outputFormat string) error {
+	if outputFormat == "yaml" {
+		outputYAML, err := yaml.Marshal(policy)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+		return nil
+	}
+
+	outputJSON, err := json.MarshalIndent(policy, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	fmt.Println(string(outputJSON))
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v2"
+)
+
+// UtilizationReport computes the utilization of each pool from the reservations in the table.
+func UtilizationReport(ctx context.Context, client *dynamodb.Client, tableName string, pools []helpers.Pool) ([]helpers.Utilization, error) {
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("no pools configured, set pools in the config file or pass --pool")
+	}
+
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing CIDRs: %w", err)
+	}
+
+	var report []helpers.Utilization
+
+	for _, pool := range pools {
+		utilization, err := helpers.ComputeUtilization(pool, existingCIDRs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		report = append(report, utilization)
+	}
+
+	return report, nil
+}
+
+// VpcUtilizationReport computes how much of each VPC's own CIDR is used by its subnets,
+// based on the child reservations recorded or imported for the VPC.
+func VpcUtilizationReport(ctx context.Context, client *dynamodb.Client, tableName string, vpcIDs []string) ([]helpers.Utilization, error) {
+	reservations, err := FetchReservations(ctx, client, tableName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var report []helpers.Utilization
+
+	for _, vpcID := range vpcIDs {
+		var parent *Reservation
+
+		for i := range reservations {
+			if !reservations[i].IsChild() && reservations[i].VpcID == vpcID {
+				parent = &reservations[i]
+				break
+			}
+		}
+
+		if parent == nil {
+			return nil, fmt.Errorf("no reservation found for VPC %s", vpcID)
+		}
+
+		var subnetCIDRs []string
+
+		for _, reservation := range reservations {
+			if reservation.ParentCIDR == parent.CIDR {
+				subnetCIDRs = append(subnetCIDRs, reservation.CIDR)
+			}
+		}
+
+		utilization, err := helpers.ComputeUtilization(helpers.Pool{Name: vpcID, CIDR: parent.CIDR}, subnetCIDRs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		report = append(report, utilization)
+	}
+
+	return report, nil
+}
+
+// PrintUtilizationReport writes the utilization report to stdout in the requested output format.
+func PrintUtilizationReport(report []helpers.Utilization, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(report, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(report)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Pool", "CIDR", "Total", "Reserved", "Free", "Used %", "Reservations", "By Prefix", "Largest Free Block", "Fragmentation"})
+		table.SetAutoWrapText(false)
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, utilization := range report {
+			table.Append([]string{
+				utilization.Pool.Name,
+				utilization.Pool.CIDR,
+				strconv.FormatUint(utilization.TotalAddresses, 10),
+				strconv.FormatUint(utilization.ReservedAddresses, 10),
+				strconv.FormatUint(utilization.FreeAddresses, 10),
+				strconv.FormatFloat(utilization.UsedPercent, 'f', 2, 64),
+				strconv.Itoa(utilization.Reservations),
+				formatPrefixCounts(utilization.PrefixCounts),
+				utilization.LargestFreeBlock,
+				strconv.FormatFloat(utilization.FragmentationRatio, 'f', 2, 64),
+			})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
+
+// ListFreeCIDRs returns the free CIDR blocks inside within, based on the reservations in the table.
+func ListFreeCIDRs(ctx context.Context, client *dynamodb.Client, tableName string, within string, prefixSize int) ([]string, error) {
+	existingCIDRs, err := FetchExistingCIDRs(client, tableName)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing CIDRs: %w", err)
+	}
+
+	return helpers.FreeCIDRs(within, existingCIDRs, prefixSize)
+}
+
+// PrintFreeCIDRs writes the free CIDR blocks to stdout in the requested output format.
+func PrintFreeCIDRs(freeCIDRs []string, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(freeCIDRs, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(freeCIDRs)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"CIDR", "Addresses"})
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, cidr := range freeCIDRs {
+			_, network, err := net.ParseCIDR(cidr)
+
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			prefix, bits := network.Mask.Size()
+			table.Append([]string{cidr, strconv.FormatUint(uint64(1)<<(bits-prefix), 10)})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
+
+// formatPrefixCounts renders prefix counts as "/16:2 /24:5", ordered by prefix length.
+func formatPrefixCounts(counts map[string]int) string {
+	prefixes := make([]string, 0, len(counts))
+
+	for prefix := range counts {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(prefixes[i], "/"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(prefixes[j], "/"))
+		return a < b
+	})
+
+	var parts []string
+
+	for _, prefix := range prefixes {
+		parts = append(parts, fmt.Sprintf("%s:%d", prefix, counts[prefix]))
+	}
+
+	return strings.Join(parts, " ")
+}
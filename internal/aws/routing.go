@@ -0,0 +1,366 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// RoutingPath connects two VPCs, through a Transit Gateway route table or a VPC peering connection.
+type RoutingPath struct {
+	VpcA string `json:"vpcA" yaml:"vpcA"`
+	VpcB string `json:"vpcB" yaml:"vpcB"`
+	Path string `json:"path" yaml:"path"`
+}
+
+// RoutingConflict is a pair of connected VPCs whose reserved or live CIDRs overlap.
+type RoutingConflict struct {
+	VpcA  string `json:"vpcA" yaml:"vpcA"`
+	CIDRA string `json:"cidrA" yaml:"cidrA"`
+	VpcB  string `json:"vpcB" yaml:"vpcB"`
+	CIDRB string `json:"cidrB" yaml:"cidrB"`
+	Path  string `json:"path" yaml:"path"`
+}
+
+// RoutingDiscovery is everything found in one account that is needed to check routing.
+type RoutingDiscovery struct {
+	VpcCIDRs map[string][]string
+	Paths    []RoutingPath
+}
+
+// DiscoverRouting finds the live CIDRs of the VPCs visible to client, and the paths that connect VPCs
+// through the Transit Gateway attachments, Transit Gateway route tables and active peering connections.
+func DiscoverRouting(ctx context.Context, client *ec2.Client, logger *log.Logger) (RoutingDiscovery, error) {
+	discovery := RoutingDiscovery{VpcCIDRs: map[string][]string{}}
+
+	vpcPaginator := ec2.NewDescribeVpcsPaginator(client, &ec2.DescribeVpcsInput{})
+
+	for vpcPaginator.HasMorePages() {
+		output, err := vpcPaginator.NextPage(ctx)
+
+		if err != nil {
+			return RoutingDiscovery{}, fmt.Errorf("failed to describe VPCs: %v", err)
+		}
+
+		for _, vpc := range output.Vpcs {
+			for _, association := range vpc.CidrBlockAssociationSet {
+				if association.CidrBlockState != nil && association.CidrBlockState.State == types.VpcCidrBlockStateCodeAssociated {
+					discovery.addCIDR(aws.ToString(vpc.VpcId), aws.ToString(association.CidrBlock))
+				}
+			}
+		}
+	}
+
+	tgwPaths, err := discoverTransitGatewayPaths(ctx, client, logger)
+
+	if err != nil {
+		return RoutingDiscovery{}, err
+	}
+
+	discovery.Paths = append(discovery.Paths, tgwPaths...)
+
+	peeringPaginator := ec2.NewDescribeVpcPeeringConnectionsPaginator(client, &ec2.DescribeVpcPeeringConnectionsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("status-code"),
+				Values: []string{string(types.VpcPeeringConnectionStateReasonCodeActive)},
+			},
+		},
+	})
+
+	for peeringPaginator.HasMorePages() {
+		output, err := peeringPaginator.NextPage(ctx)
+
+		if err != nil {
+			return RoutingDiscovery{}, fmt.Errorf("failed to describe VPC peering connections: %v", err)
+		}
+
+		for _, peering := range output.VpcPeeringConnections {
+			requester := peering.RequesterVpcInfo
+			accepter := peering.AccepterVpcInfo
+
+			if requester == nil || accepter == nil {
+				continue
+			}
+
+			// The peer VPC may live in an account we can't describe, so keep the CIDRs the peering reports
+			for _, vpcInfo := range []*types.VpcPeeringConnectionVpcInfo{requester, accepter} {
+				for _, cidrBlock := range vpcInfo.CidrBlockSet {
+					discovery.addCIDR(aws.ToString(vpcInfo.VpcId), aws.ToString(cidrBlock.CidrBlock))
+				}
+			}
+
+			discovery.Paths = append(discovery.Paths, RoutingPath{
+				VpcA: aws.ToString(requester.VpcId),
+				VpcB: aws.ToString(accepter.VpcId),
+				Path: "peering " + aws.ToString(peering.VpcPeeringConnectionId),
+			})
+		}
+	}
+
+	return discovery, nil
+}
+
+// discoverTransitGatewayPaths connects every VPC attachment to the VPC attachments its
+// associated route table has active routes to, whether they are propagated or static.
+func discoverTransitGatewayPaths(ctx context.Context, client *ec2.Client, logger *log.Logger) ([]RoutingPath, error) {
+	var paths []RoutingPath
+
+	// VPC attachments by associated route table
+	routeTables := map[string][]types.TransitGatewayAttachment{}
+	var routeTableIDs []string
+	var attachmentIDs []string
+
+	paginator := ec2.NewDescribeTransitGatewayAttachmentsPaginator(client, &ec2.DescribeTransitGatewayAttachmentsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("resource-type"),
+				Values: []string{string(types.TransitGatewayAttachmentResourceTypeVpc)},
+			},
+			{
+				Name:   aws.String("state"),
+				Values: []string{string(types.TransitGatewayAttachmentStateAvailable)},
+			},
+		},
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe Transit Gateway attachments: %v", err)
+		}
+
+		for _, attachment := range output.TransitGatewayAttachments {
+			attachmentIDs = append(attachmentIDs, aws.ToString(attachment.TransitGatewayAttachmentId))
+
+			if attachment.Association == nil || attachment.Association.TransitGatewayRouteTableId == nil {
+				continue
+			}
+
+			routeTableID := aws.ToString(attachment.Association.TransitGatewayRouteTableId)
+
+			if _, ok := routeTables[routeTableID]; !ok {
+				routeTableIDs = append(routeTableIDs, routeTableID)
+			}
+
+			routeTables[routeTableID] = append(routeTables[routeTableID], attachment)
+		}
+	}
+
+	for _, routeTableID := range routeTableIDs {
+		// Route tables are only visible to the Transit Gateway owner, other accounts report them from their side
+		output, err := client.SearchTransitGatewayRoutes(ctx, activeRoutesInput(routeTableID, "attachment.resource-type", string(types.TransitGatewayAttachmentResourceTypeVpc)))
+
+		if err != nil {
+			logger.Debugf("Skipping Transit Gateway route table %s: %v", routeTableID, err)
+			continue
+		}
+
+		routes := output.Routes
+
+		if aws.ToBool(output.AdditionalRoutesAvailable) {
+			// Searches can't be paged, so narrow them down to one attachment at a time
+			logger.Debugf("Transit Gateway route table %s has more than %d routes, searching them by attachment", routeTableID, maxTransitGatewayRoutes)
+			routes, err = searchRoutesByAttachment(ctx, client, routeTableID, attachmentIDs)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, route := range routes {
+			for _, target := range route.TransitGatewayAttachments {
+				if target.ResourceType != types.TransitGatewayAttachmentResourceTypeVpc {
+					continue
+				}
+
+				for _, source := range routeTables[routeTableID] {
+					paths = append(paths, RoutingPath{
+						VpcA: aws.ToString(source.ResourceId),
+						VpcB: aws.ToString(target.ResourceId),
+						Path: fmt.Sprintf("%s route table %s", aws.ToString(source.TransitGatewayId), routeTableID),
+					})
+				}
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// maxTransitGatewayRoutes is the most routes a single Transit Gateway route search returns.
+const maxTransitGatewayRoutes = 1000
+
+// activeRoutesInput searches a Transit Gateway route table for its active routes that match filter.
+func activeRoutesInput(routeTableID string, filter string, value string) *ec2.SearchTransitGatewayRoutesInput {
+	return &ec2.SearchTransitGatewayRoutesInput{
+		TransitGatewayRouteTableId: aws.String(routeTableID),
+		MaxResults:                 aws.Int32(maxTransitGatewayRoutes),
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: []string{string(types.TransitGatewayRouteStateActive)},
+			},
+			{
+				Name:   aws.String(filter),
+				Values: []string{value},
+			},
+		},
+	}
+}
+
+// searchRoutesByAttachment returns the active routes of a Transit Gateway route table to each of the
+// attachments. It fails rather than return some of the routes when an attachment alone has more routes
+// than a search returns.
+func searchRoutesByAttachment(ctx context.Context, client *ec2.Client, routeTableID string, attachmentIDs []string) ([]types.TransitGatewayRoute, error) {
+	var routes []types.TransitGatewayRoute
+
+	for _, attachmentID := range attachmentIDs {
+		output, err := client.SearchTransitGatewayRoutes(ctx, activeRoutesInput(routeTableID, "attachment.transit-gateway-attachment-id", attachmentID))
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to search Transit Gateway route table %s for attachment %s: %v", routeTableID, attachmentID, err)
+		}
+
+		if aws.ToBool(output.AdditionalRoutesAvailable) {
+			return nil, fmt.Errorf("Transit Gateway route table %s has more than %d routes to attachment %s, paths through it can't be checked", routeTableID, maxTransitGatewayRoutes, attachmentID)
+		}
+
+		routes = append(routes, output.Routes...)
+	}
+
+	return routes, nil
+}
+
+func (d RoutingDiscovery) addCIDR(vpcID string, cidr string) {
+	for _, existing := range d.VpcCIDRs[vpcID] {
+		if existing == cidr {
+			return
+		}
+	}
+
+	d.VpcCIDRs[vpcID] = append(d.VpcCIDRs[vpcID], cidr)
+}
+
+// CheckRouting flags every pair of connected VPCs whose CIDRs overlap. The CIDRs of a VPC are its
+// live CIDRs from the discoveries together with the top level reservations recorded for it.
+func CheckRouting(ctx context.Context, client *dynamodb.Client, tableName string, discoveries []RoutingDiscovery) ([]RoutingConflict, error) {
+	merged := RoutingDiscovery{VpcCIDRs: map[string][]string{}}
+
+	for _, discovery := range discoveries {
+		for vpcID, cidrs := range discovery.VpcCIDRs {
+			for _, cidr := range cidrs {
+				merged.addCIDR(vpcID, cidr)
+			}
+		}
+
+		merged.Paths = append(merged.Paths, discovery.Paths...)
+	}
+
+	reservations, err := FetchReservations(ctx, client, tableName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reservation := range reservations {
+		if !reservation.IsChild() && reservation.VpcID != "" {
+			merged.addCIDR(reservation.VpcID, reservation.CIDR)
+		}
+	}
+
+	var conflicts []RoutingConflict
+	seen := map[string]bool{}
+
+	for _, path := range merged.Paths {
+		if path.VpcA == path.VpcB {
+			continue
+		}
+
+		// A route in each direction is the same connection, report it once
+		vpcA, vpcB := path.VpcA, path.VpcB
+
+		if vpcB < vpcA {
+			vpcA, vpcB = vpcB, vpcA
+		}
+
+		for _, cidrA := range merged.VpcCIDRs[vpcA] {
+			_, networkA, err := net.ParseCIDR(cidrA)
+
+			if err != nil {
+				continue
+			}
+
+			for _, cidrB := range findOverlappingCIDRs(networkA, merged.VpcCIDRs[vpcB]) {
+				conflict := RoutingConflict{VpcA: vpcA, CIDRA: cidrA, VpcB: vpcB, CIDRB: cidrB, Path: path.Path}
+				key := fmt.Sprintf("%v", conflict)
+
+				if !seen[key] {
+					seen[key] = true
+					conflicts = append(conflicts, conflict)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].VpcA < conflicts[j].VpcA
+	})
+
+	return conflicts, nil
+}
+
+// PrintRoutingConflicts writes the routing conflicts to stdout in the requested output format.
+func PrintRoutingConflicts(conflicts []RoutingConflict, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(conflicts, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(conflicts)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"VPC A", "CIDR A", "VPC B", "CIDR B", "Path"})
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, conflict := range conflicts {
+			table.Append([]string{conflict.VpcA, conflict.CIDRA, conflict.VpcB, conflict.CIDRB, conflict.Path})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// GetS3Client returns an S3 client, sent to endpoint instead of the AWS endpoint when it is set.
+// Local S3 endpoints, such as LocalStack, need path-style addressing.
+func GetS3Client(cfg aws.Config, endpoint string, usePathStyle bool) (*s3.Client, error) {
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+
+		o.UsePathStyle = usePathStyle
+	})
+
+	return client, nil
+}
+
+// IsS3URI reports whether a location is an s3://bucket/key URI.
+func IsS3URI(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// ParseS3URI splits an s3://bucket/key URI into its bucket and key.
+func ParseS3URI(uri string) (string, string, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(uri, "s3://"), "/")
+
+	if !IsS3URI(uri) || bucket == "" {
+		return "", "", fmt.Errorf("invalid S3 URI %s, expected s3://bucket/key", uri)
+	}
+
+	return bucket, key, nil
+}
+
+// PutS3Object uploads body to an s3://bucket/key URI and returns the version ID when the bucket is versioned.
+func PutS3Object(ctx context.Context, client *s3.Client, uri string, body []byte) (string, error) {
+	bucket, key, err := ParseS3URI(uri)
+
+	if err != nil {
+		return "", err
+	}
+
+	output, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %v", uri, err)
+	}
+
+	return aws.ToString(output.VersionId), nil
+}
+
+// GetS3Object downloads an s3://bucket/key URI, a non-empty versionID reads that version of the object.
+func GetS3Object(ctx context.Context, client *s3.Client, uri string, versionID string) ([]byte, error) {
+	bucket, key, err := ParseS3URI(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+
+	output, err := client.GetObject(ctx, input)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", uri, err)
+	}
+
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
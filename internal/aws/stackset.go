@@ -0,0 +1,303 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// stackSetOperationPollInterval is how often the status of a StackSet operation is polled.
+const stackSetOperationPollInterval = 10 * time.Second
+
+// StackSetOptions configure where a service-managed StackSet is deployed in the organization.
+type StackSetOptions struct {
+	// OrganizationalUnitIDs to deploy to, AccountIDs narrows the deployment to these accounts within them.
+	OrganizationalUnitIDs []string
+	AccountIDs            []string
+	Regions               []string
+
+	// AutoDeploy deploys the stack to accounts added to the OUs later, and removes it from accounts that leave.
+	AutoDeploy                   bool
+	RetainStacksOnAccountRemoval bool
+
+	// DelegatedAdmin calls StackSets as a delegated administrator instead of from the management account.
+	DelegatedAdmin bool
+}
+
+// StackInstanceStatus is the deployment status of a StackSet in one account and region.
+type StackInstanceStatus struct {
+	Account              string `json:"account" yaml:"account"`
+	OrganizationalUnitID string `json:"organizationalUnitId,omitempty" yaml:"organizationalUnitId,omitempty"`
+	Region               string `json:"region" yaml:"region"`
+	Status               string `json:"status" yaml:"status"`
+	DetailedStatus       string `json:"detailedStatus,omitempty" yaml:"detailedStatus,omitempty"`
+	Reason               string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+func (o StackSetOptions) callAs() types.CallAs {
+	if o.DelegatedAdmin {
+		return types.CallAsDelegatedAdmin
+	}
+
+	return types.CallAsSelf
+}
+
+// DeployCFNStackSet creates or updates a service-managed StackSet and deploys it to the OUs and accounts in opts.
+// It waits for each StackSet operation to finish and fails with the accounts whose deployment failed.
+func DeployCFNStackSet(ctx context.Context, client *cloudformation.Client, stackSetName string, templateBody string, opts StackSetOptions, timeout time.Duration, logger *log.Logger) error {
+	if len(opts.OrganizationalUnitIDs) == 0 {
+		return fmt.Errorf("service-managed StackSets deploy to organizational units, at least one OU is required")
+	}
+
+	if len(opts.Regions) == 0 {
+		return fmt.Errorf("at least one region is required")
+	}
+
+	autoDeployment := &types.AutoDeployment{
+		Enabled: aws.Bool(opts.AutoDeploy),
+	}
+
+	// Retaining stacks is only valid together with auto-deployment
+	if opts.AutoDeploy {
+		autoDeployment.RetainStacksOnAccountRemoval = aws.Bool(opts.RetainStacksOnAccountRemoval)
+	}
+
+	capabilities := []types.Capability{
+		types.CapabilityCapabilityIam,
+		types.CapabilityCapabilityNamedIam,
+	}
+
+	_, err := client.DescribeStackSet(ctx, &cloudformation.DescribeStackSetInput{
+		StackSetName: aws.String(stackSetName),
+		CallAs:       opts.callAs(),
+	})
+
+	var notFound *types.StackSetNotFoundException
+
+	switch {
+	case errors.As(err, &notFound):
+		logger.Debugf("Creating StackSet %s", stackSetName)
+		_, err = client.CreateStackSet(ctx, &cloudformation.CreateStackSetInput{
+			StackSetName:    aws.String(stackSetName),
+			TemplateBody:    aws.String(templateBody),
+			PermissionModel: types.PermissionModelsServiceManaged,
+			AutoDeployment:  autoDeployment,
+			Capabilities:    capabilities,
+			CallAs:          opts.callAs(),
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to create StackSet: %v", err)
+		}
+
+	case err != nil:
+		return fmt.Errorf("failed to describe StackSet: %v", err)
+
+	default:
+		logger.Debugf("Updating StackSet %s and its existing stack instances", stackSetName)
+		output, err := client.UpdateStackSet(ctx, &cloudformation.UpdateStackSetInput{
+			StackSetName:   aws.String(stackSetName),
+			TemplateBody:   aws.String(templateBody),
+			AutoDeployment: autoDeployment,
+			Capabilities:   capabilities,
+			CallAs:         opts.callAs(),
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to update StackSet: %v", err)
+		}
+
+		err = waitForStackSetOperation(ctx, client, stackSetName, aws.ToString(output.OperationId), opts, timeout, logger)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	targets := &types.DeploymentTargets{
+		OrganizationalUnitIds: opts.OrganizationalUnitIDs,
+	}
+
+	if len(opts.AccountIDs) > 0 {
+		targets.Accounts = opts.AccountIDs
+		targets.AccountFilterType = types.AccountFilterTypeIntersection
+	}
+
+	logger.Debugf("Deploying StackSet %s to %s in %s", stackSetName, strings.Join(opts.OrganizationalUnitIDs, ", "), strings.Join(opts.Regions, ", "))
+	output, err := client.CreateStackInstances(ctx, &cloudformation.CreateStackInstancesInput{
+		StackSetName:      aws.String(stackSetName),
+		DeploymentTargets: targets,
+		Regions:           opts.Regions,
+		CallAs:            opts.callAs(),
+		OperationPreferences: &types.StackSetOperationPreferences{
+			RegionConcurrencyType:   types.RegionConcurrencyTypeParallel,
+			MaxConcurrentPercentage: aws.Int32(100),
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create stack instances: %v", err)
+	}
+
+	return waitForStackSetOperation(ctx, client, stackSetName, aws.ToString(output.OperationId), opts, timeout, logger)
+}
+
+// waitForStackSetOperation waits for a StackSet operation to finish. When it fails, the error lists
+// the accounts and regions the operation failed in.
+func waitForStackSetOperation(ctx context.Context, client *cloudformation.Client, stackSetName string, operationID string, opts StackSetOptions, timeout time.Duration, logger *log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastStatus types.StackSetOperationStatus
+
+	for {
+		output, err := client.DescribeStackSetOperation(ctx, &cloudformation.DescribeStackSetOperationInput{
+			StackSetName: aws.String(stackSetName),
+			OperationId:  aws.String(operationID),
+			CallAs:       opts.callAs(),
+		})
+
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("failed to describe StackSet operation: %v", err)
+		}
+
+		if err == nil {
+			status := output.StackSetOperation.Status
+
+			if status != lastStatus {
+				logger.Infof("StackSet %s operation %s %s", stackSetName, output.StackSetOperation.Action, status)
+				lastStatus = status
+			}
+
+			switch status {
+			case types.StackSetOperationStatusSucceeded:
+				return nil
+			case types.StackSetOperationStatusFailed, types.StackSetOperationStatusStopped:
+				return fmt.Errorf("StackSet %s operation %s: %s", stackSetName, strings.ToLower(string(status)), strings.Join(failedStackSetResults(ctx, client, stackSetName, operationID, opts), "; "))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for StackSet %s operation %s", timeout, stackSetName, operationID)
+		case <-time.After(stackSetOperationPollInterval):
+		}
+	}
+}
+
+// failedStackSetResults returns "account region: reason" for every account a StackSet operation failed in.
+func failedStackSetResults(ctx context.Context, client *cloudformation.Client, stackSetName string, operationID string, opts StackSetOptions) []string {
+	var failed []string
+
+	paginator := cloudformation.NewListStackSetOperationResultsPaginator(client, &cloudformation.ListStackSetOperationResultsInput{
+		StackSetName: aws.String(stackSetName),
+		OperationId:  aws.String(operationID),
+		CallAs:       opts.callAs(),
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return append(failed, fmt.Sprintf("failed to list operation results: %v", err))
+		}
+
+		for _, result := range output.Summaries {
+			if result.Status == types.StackSetOperationResultStatusFailed {
+				failed = append(failed, fmt.Sprintf("%s %s: %s", aws.ToString(result.Account), aws.ToString(result.Region), aws.ToString(result.StatusReason)))
+			}
+		}
+	}
+
+	return failed
+}
+
+// GetStackInstanceStatuses returns the deployment status of a StackSet in every account and region.
+func GetStackInstanceStatuses(ctx context.Context, client *cloudformation.Client, stackSetName string, opts StackSetOptions) ([]StackInstanceStatus, error) {
+	var statuses []StackInstanceStatus
+
+	paginator := cloudformation.NewListStackInstancesPaginator(client, &cloudformation.ListStackInstancesInput{
+		StackSetName: aws.String(stackSetName),
+		CallAs:       opts.callAs(),
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to list stack instances: %v", err)
+		}
+
+		for _, instance := range output.Summaries {
+			status := StackInstanceStatus{
+				Account:              aws.ToString(instance.Account),
+				OrganizationalUnitID: aws.ToString(instance.OrganizationalUnitId),
+				Region:               aws.ToString(instance.Region),
+				Status:               string(instance.Status),
+				Reason:               aws.ToString(instance.StatusReason),
+			}
+
+			if instance.StackInstanceStatus != nil {
+				status.DetailedStatus = string(instance.StackInstanceStatus.DetailedStatus)
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+// PrintStackInstanceStatuses writes the stack instance statuses to stdout in the requested output format.
+func PrintStackInstanceStatuses(statuses []StackInstanceStatus, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(statuses, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(statuses)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Account", "OU", "Region", "Status", "Detailed Status", "Reason"})
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, status := range statuses {
+			table.Append([]string{status.Account, status.OrganizationalUnitID, status.Region, status.Status, status.DetailedStatus, status.Reason})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
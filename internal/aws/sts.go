@@ -1,20 +1,80 @@
 package aws
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
-func GetStsClient(cfg aws.Config) (*sts.Client, error) {
-	stsClient := sts.NewFromConfig(cfg)
+// Limits STS puts on the duration of an assumed role session.
+const (
+	minAssumeRoleDuration = 15 * time.Minute
+	maxAssumeRoleDuration = 12 * time.Hour
+)
+
+// GetStsClient returns an STS client, sent to endpoint instead of the AWS endpoint when it is set.
+func GetStsClient(cfg aws.Config, endpoint string) (*sts.Client, error) {
+	stsClient := sts.NewFromConfig(cfg, func(o *sts.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
 
 	return stsClient, nil
 }
 
-func AssumeRole(cfg aws.Config, stsClient *sts.Client, roleArn string) (aws.Config, error) {
+// AssumeRoleOptions are the optional parameters of the AssumeRole call.
+type AssumeRoleOptions struct {
+	ExternalID      string
+	RoleSessionName string
+
+	// Duration of the session, zero uses the STS default of one hour.
+	Duration time.Duration
+
+	// MFASerial is the serial number or ARN of the MFA device. The token code is read from
+	// MFATokenProvider, or from stdin when MFATokenProvider is not set.
+	MFASerial        string
+	MFATokenProvider func() (string, error)
+
+	// Policy is an inline session policy that further restricts the role's permissions.
+	Policy string
+}
+
+func AssumeRole(cfg aws.Config, stsClient *sts.Client, roleArn string, opts AssumeRoleOptions) (aws.Config, error) {
+	if opts.Duration != 0 && (opts.Duration < minAssumeRoleDuration || opts.Duration > maxAssumeRoleDuration) {
+		return aws.Config{}, fmt.Errorf("session duration %s must be between %s and %s", opts.Duration, minAssumeRoleDuration, maxAssumeRoleDuration)
+	}
+
 	// Create a new config with the assumed role credentials
-	creds := stscreds.NewAssumeRoleProvider(stsClient, roleArn)
+	creds := stscreds.NewAssumeRoleProvider(stsClient, roleArn, func(o *stscreds.AssumeRoleOptions) {
+		if opts.ExternalID != "" {
+			o.ExternalID = aws.String(opts.ExternalID)
+		}
+
+		if opts.RoleSessionName != "" {
+			o.RoleSessionName = opts.RoleSessionName
+		}
+
+		if opts.Duration != 0 {
+			o.Duration = opts.Duration
+		}
+
+		if opts.MFASerial != "" {
+			o.SerialNumber = aws.String(opts.MFASerial)
+			o.TokenProvider = opts.MFATokenProvider
+
+			if o.TokenProvider == nil {
+				o.TokenProvider = stscreds.StdinTokenProvider
+			}
+		}
+
+		if opts.Policy != "" {
+			o.Policy = aws.String(opts.Policy)
+		}
+	})
 
 	newCfg := cfg.Copy()
 	newCfg.Credentials = aws.NewCredentialsCache(creds)
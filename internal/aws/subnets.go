@@ -0,0 +1,173 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const reservationTypeSubnet = "subnet"
+
+// RecordSubnets stores a subnet layout as child reservations of the VPC reservation for vpcCIDR.
+// The subnets are written in a single transaction, so either all of them are recorded or none are.
+func RecordSubnets(ctx context.Context, client *dynamodb.Client, tableName string, vpcCIDR string, subnets []helpers.PlannedSubnet, actor string, logger *log.Logger) error {
+	if len(subnets) > maxTransactItems {
+		return fmt.Errorf("cannot record more than %d subnets in one request, got %d", maxTransactItems, len(subnets))
+	}
+
+	_, vpcNetwork, err := net.ParseCIDR(vpcCIDR)
+
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"CIDR": &types.AttributeValueMemberS{Value: vpcNetwork.String()},
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("Got error calling GetItem: %v", err)
+	}
+
+	if output.Item == nil {
+		return fmt.Errorf("CIDR %s is not reserved, reserve it before recording its subnets", vpcNetwork.String())
+	}
+
+	var parent Reservation
+
+	if err := attributevalue.UnmarshalMap(output.Item, &parent); err != nil {
+		return fmt.Errorf("failed to unmarshal reservation: %w", err)
+	}
+
+	// A different layout recorded earlier would overlap the new subnets
+	children, err := FetchChildReservations(ctx, client, tableName, parent.CIDR)
+
+	if err != nil {
+		return err
+	}
+
+	var childCIDRs []string
+
+	for _, child := range children {
+		childCIDRs = append(childCIDRs, child.CIDR)
+	}
+
+	var transactItems []types.TransactWriteItem
+
+	for _, subnet := range subnets {
+		_, network, err := net.ParseCIDR(subnet.CIDR)
+
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		if overlaps := findOverlappingCIDRs(network, childCIDRs); len(overlaps) > 0 {
+			return fmt.Errorf("subnet %s overlaps with subnet %s already recorded for %s, release the recorded subnets first, no subnets were recorded", subnet.CIDR, overlaps[0], parent.CIDR)
+		}
+
+		child := Reservation{
+			CIDR:             subnet.CIDR,
+			AccountID:        parent.AccountID,
+			VpcID:            parent.VpcID,
+			VpcName:          parent.VpcName,
+			ReservedAt:       time.Now().Format(time.RFC3339),
+			ReservedBy:       actor,
+			Status:           "reserved",
+			ParentCIDR:       parent.CIDR,
+			Type:             reservationTypeSubnet,
+			Tier:             subnet.Tier,
+			AvailabilityZone: subnet.AvailabilityZone,
+		}
+
+		item, err := attributevalue.MarshalMap(child)
+
+		if err != nil {
+			return fmt.Errorf("Got error marshalling map: %v", err)
+		}
+
+		logger.Debugf("Adding subnet %s (%s, %s) to transaction", subnet.CIDR, subnet.Tier, subnet.AvailabilityZone)
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(tableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(CIDR)"),
+			},
+		})
+	}
+
+	_, err = client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to record subnets, none were recorded: %w", err)
+	}
+
+	return nil
+}
+
+// PrintSubnetPlan writes a subnet layout to stdout in the requested output format.
+func PrintSubnetPlan(subnets []helpers.PlannedSubnet, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		outputJSON, err := json.MarshalIndent(subnets, "", "  ")
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+
+		fmt.Println(string(outputJSON))
+
+	case "yaml":
+		outputYAML, err := yaml.Marshal(subnets)
+
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+
+		fmt.Print(string(outputYAML))
+
+	case "table", "markdown":
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Tier", "Availability Zone", "CIDR", "Addresses"})
+
+		if outputFormat == "markdown" {
+			table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			table.SetCenterSeparator("|")
+		}
+
+		for _, subnet := range subnets {
+			_, network, err := net.ParseCIDR(subnet.CIDR)
+
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			prefix, bits := network.Mask.Size()
+			table.Append([]string{subnet.Tier, subnet.AvailabilityZone, subnet.CIDR, strconv.FormatUint(uint64(1)<<(bits-prefix), 10)})
+		}
+
+		table.Render()
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	return nil
+}
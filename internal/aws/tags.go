@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/asafdavid23/vpc-cidr-manager/internal/helpers"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tags written onto a VPC to link it back to its reservation.
+const (
+	TagReservationID = "cidr-manager:reservation-id"
+	TagPool          = "cidr-manager:pool"
+	TagReservedBy    = "cidr-manager:reserved-by"
+)
+
+// TagMismatch is a VPC whose reservation ID tag names a different reservation than the one it is
+// recorded for in the table.
+type TagMismatch struct {
+	CIDR          string `json:"cidr" yaml:"cidr"`
+	VpcID         string `json:"vpcId" yaml:"vpcId"`
+	ReservationID string `json:"reservationId" yaml:"reservationId"`
+}
+
+// GetReservation returns the reservation for cidr, or nil if it is not reserved.
+func GetReservation(ctx context.Context, client *dynamodb.Client, tableName string, cidr string) (*Reservation, error) {
+	output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]dynamodbTypes.AttributeValue{
+			"CIDR": &dynamodbTypes.AttributeValueMemberS{Value: cidr},
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Got error calling GetItem: %v", err)
+	}
+
+	if output.Item == nil {
+		return nil, nil
+	}
+
+	var reservation Reservation
+
+	if err := attributevalue.UnmarshalMap(output.Item, &reservation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reservation: %w", err)
+	}
+
+	return &reservation, nil
+}
+
+// TagVpcWithReservation writes the reservation ID, pool and owner of a reservation onto its VPC,
+// so the VPC can be matched to its reservation by tag and not only by CIDR.
+// The pool tag is left out when no pool contains the reservation.
+func TagVpcWithReservation(ctx context.Context, client *ec2.Client, reservation Reservation, pools []helpers.Pool) error {
+	if reservation.VpcID == "" {
+		return fmt.Errorf("reservation %s is not linked to a VPC", reservation.CIDR)
+	}
+
+	tags := []types.Tag{
+		{Key: aws.String(TagReservationID), Value: aws.String(reservation.CIDR)},
+		{Key: aws.String(TagReservedBy), Value: aws.String(reservation.ReservedBy)},
+	}
+
+	pool, err := helpers.FindContainingPool(pools, reservation.CIDR)
+
+	if err != nil {
+		return err
+	}
+
+	if pool != nil {
+		tags = append(tags, types.Tag{Key: aws.String(TagPool), Value: aws.String(pool.Name)})
+	}
+
+	_, err = client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{reservation.VpcID},
+		Tags:      tags,
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to tag VPC %s: %v", reservation.VpcID, err)
+	}
+
+	return nil
+}
+
+// ReservationIDFromTags returns the reservation ID a VPC was tagged with, or an empty string.
+func ReservationIDFromTags(tags []types.Tag) string {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == TagReservationID {
+			return aws.ToString(tag.Value)
+		}
+	}
+
+	return ""
+}
+
+// VerifyVpcTags compares the reservation ID each linked VPC is tagged with against the reservation
+// recorded for it. Untagged VPCs and VPCs that can't be described with the client, such as VPCs in
+// other accounts, are skipped.
+func VerifyVpcTags(ctx context.Context, client *ec2.Client, reservations []Reservation, logger *log.Logger) []TagMismatch {
+	var mismatches []TagMismatch
+
+	for _, reservation := range reservations {
+		if reservation.VpcID == "" {
+			continue
+		}
+
+		output, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+			VpcIds: []string{reservation.VpcID},
+		})
+
+		if err != nil || len(output.Vpcs) == 0 {
+			logger.Debugf("Skipping tag check of VPC %s: %v", reservation.VpcID, err)
+			continue
+		}
+
+		reservationID := ReservationIDFromTags(output.Vpcs[0].Tags)
+
+		if reservationID != "" && reservationID != reservation.CIDR {
+			mismatches = append(mismatches, TagMismatch{
+				CIDR:          reservation.CIDR,
+				VpcID:         reservation.VpcID,
+				ReservationID: reservationID,
+			})
+		}
+	}
+
+	return mismatches
+}
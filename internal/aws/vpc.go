@@ -3,28 +3,33 @@ package aws
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
+// VPCInfo is a VPC as import-cidr records it. Its attribute names match Reservation, so imported VPCs
+// read back like any other reservation.
 type VPCInfo struct {
-	CIDR       string    `json:"cidrBlock"`
-	AccountID  string    `json:"accountId"`
-	VpcID      string    `json:"vpcId"`
-	VpcName    string    `json:"vpcName"`
-	ReservedAt time.Time `json:"reservedAt"`
-	ReservedBy string    `json:"reservedBy"`
-	Status     string    `json:"status"`
+	CIDR       string    `dynamodbav:"CIDR" json:"cidrBlock"`
+	AccountID  string    `dynamodbav:"AccountID,omitempty" json:"accountId"`
+	VpcID      string    `dynamodbav:"VpcId" json:"vpcId"`
+	VpcName    string    `dynamodbav:"VpcName" json:"vpcName"`
+	ReservedAt time.Time `dynamodbav:"ReservedAt" json:"reservedAt"`
+	ReservedBy string    `dynamodbav:"ReservedBy" json:"reservedBy"`
+	Status     string    `dynamodbav:"Status" json:"status"`
+	Region     string    `dynamodbav:"Region,omitempty" json:"region,omitempty"`
 }
 
-func GetEc2Client(cfg aws.Config) (*ec2.Client, error) {
-
-	client := ec2.NewFromConfig(cfg)
+// GetEc2Client returns an EC2 client, sent to endpoint instead of the AWS endpoint when it is set.
+func GetEc2Client(cfg aws.Config, endpoint string) (*ec2.Client, error) {
+	client := ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
 
 	if client == nil {
 		return nil, fmt.Errorf("EC2 client is nil")
@@ -33,37 +38,16 @@ func GetEc2Client(cfg aws.Config) (*ec2.Client, error) {
 	return client, nil
 }
 
-func GetVpcInfo(client *ec2.Client, vpcId string) (VPCInfo, error) {
+// GetVpcInfo describes a VPC as a reservation made by actor. The account is the VPC's owner,
+// so VPCs described through an assumed role are recorded under the spoke account.
+func GetVpcInfo(ctx context.Context, client *ec2.Client, vpcId string, actor string) (VPCInfo, error) {
 	var vpcInfo VPCInfo
-	var sessionName string
-
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-
-	if err != nil {
-		return VPCInfo{}, fmt.Errorf("unable to load SDK config, %v", err)
-	}
-
-	stsClient := sts.NewFromConfig(cfg)
-
-	output, err := stsClient.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
-
-	if err != nil {
-		return VPCInfo{}, fmt.Errorf("unable to get caller identity, %v", err)
-	}
-
-	arnParts := strings.Split(*output.Arn, "/")
-
-	if len(arnParts) > 2 {
-		sessionName = arnParts[2]
-	} else {
-		return VPCInfo{}, fmt.Errorf("unable to parse session name from ARN: %s", *output.Arn)
-	}
 
 	input := &ec2.DescribeVpcsInput{
 		VpcIds: []string{vpcId},
 	}
 
-	result, err := client.DescribeVpcs(context.TODO(), input)
+	result, err := client.DescribeVpcs(ctx, input)
 
 	if err != nil {
 		return VPCInfo{}, fmt.Errorf("%v", err)
@@ -76,10 +60,10 @@ func GetVpcInfo(client *ec2.Client, vpcId string) (VPCInfo, error) {
 	for _, vpc := range result.Vpcs {
 		vpcInfo = VPCInfo{
 			CIDR:       *vpc.CidrBlock,
-			AccountID:  *output.Account,
+			AccountID:  aws.ToString(vpc.OwnerId),
 			VpcID:      *vpc.VpcId,
 			ReservedAt: time.Now(),
-			ReservedBy: sessionName,
+			ReservedBy: actor,
 			Status:     "reserved",
 		}
 
@@ -92,9 +76,55 @@ func GetVpcInfo(client *ec2.Client, vpcId string) (VPCInfo, error) {
 		}
 	}
 
-	if err != nil {
-		return VPCInfo{}, fmt.Errorf("Error pushing VPC info to DynamoDB: %v", err)
+	return vpcInfo, nil
+}
+
+// SubnetInfo describes a subnet discovered in a VPC.
+type SubnetInfo struct {
+	CIDR                    string `json:"cidrBlock"`
+	SubnetID                string `json:"subnetId"`
+	SubnetName              string `json:"subnetName"`
+	AvailabilityZone        string `json:"availabilityZone"`
+	AvailableIPAddressCount int32  `json:"availableIpAddressCount"`
+}
+
+// GetSubnetInfo returns the subnets of a VPC.
+func GetSubnetInfo(client *ec2.Client, vpcId string) ([]SubnetInfo, error) {
+	var subnets []SubnetInfo
+
+	paginator := ec2.NewDescribeSubnetsPaginator(client, &ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpcId},
+			},
+		},
+	})
+
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe subnets of VPC %s: %v", vpcId, err)
+		}
+
+		for _, subnet := range output.Subnets {
+			subnetInfo := SubnetInfo{
+				CIDR:                    aws.ToString(subnet.CidrBlock),
+				SubnetID:                aws.ToString(subnet.SubnetId),
+				AvailabilityZone:        aws.ToString(subnet.AvailabilityZone),
+				AvailableIPAddressCount: aws.ToInt32(subnet.AvailableIpAddressCount),
+			}
+
+			for _, tag := range subnet.Tags {
+				if aws.ToString(tag.Key) == "Name" {
+					subnetInfo.SubnetName = aws.ToString(tag.Value)
+				}
+			}
+
+			subnets = append(subnets, subnetInfo)
+		}
 	}
 
-	return vpcInfo, nil
+	return subnets, nil
 }
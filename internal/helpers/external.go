@@ -0,0 +1,151 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Sources of address ranges that are used outside of AWS.
+const (
+	SourceOnPrem  = "onprem"
+	SourcePartner = "partner"
+	SourceVPN     = "vpn"
+)
+
+// ExternalSources lists the sources of ranges that block allocation but don't exist in AWS.
+var ExternalSources = []string{SourceOnPrem, SourcePartner, SourceVPN}
+
+// ExternalRange is an address range used by a data center, partner network or VPN clients.
+type ExternalRange struct {
+	CIDR        string `yaml:"cidr"`
+	Source      string `yaml:"source"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// IsExternalSource checks if source is one of the ExternalSources.
+func IsExternalSource(source string) bool {
+	for _, externalSource := range ExternalSources {
+		if source == externalSource {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadExternalRanges loads external ranges from a CSV file with a header row
+// (cidr,source,name,description) or from a YAML file with a top level ranges list.
+func LoadExternalRanges(filePath string) ([]ExternalRange, error) {
+	file, err := os.Open(filePath)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+
+	defer file.Close()
+
+	var ranges []ExternalRange
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		ranges, err = parseExternalRangesCSV(file)
+	case ".yaml", ".yml":
+		ranges, err = parseExternalRangesYAML(file)
+	default:
+		return nil, fmt.Errorf("unsupported file type %s, expected .csv, .yaml or .yml", filepath.Ext(filePath))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ranges {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(ranges[i].CIDR))
+
+		if err != nil {
+			return nil, fmt.Errorf("range %d: error parsing CIDR: %v", i+1, err)
+		}
+
+		ranges[i].CIDR = network.String()
+		ranges[i].Source = strings.ToLower(strings.TrimSpace(ranges[i].Source))
+
+		if !IsExternalSource(ranges[i].Source) {
+			return nil, fmt.Errorf("range %d: unsupported source %q, expected one of %s", i+1, ranges[i].Source, strings.Join(ExternalSources, ", "))
+		}
+	}
+
+	return ranges, nil
+}
+
+func parseExternalRangesCSV(r io.Reader) ([]ExternalRange, error) {
+	records, err := csv.NewReader(r).ReadAll()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %v", err)
+	}
+
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV must have a header row and at least one range")
+	}
+
+	columns := map[string]int{}
+
+	for i, header := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(header))] = i
+	}
+
+	for _, required := range []string{"cidr", "source"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var ranges []ExternalRange
+
+	for _, record := range records[1:] {
+		ranges = append(ranges, ExternalRange{
+			CIDR:        field(record, "cidr"),
+			Source:      field(record, "source"),
+			Name:        field(record, "name"),
+			Description: field(record, "description"),
+		})
+	}
+
+	return ranges, nil
+}
+
+func parseExternalRangesYAML(r io.Reader) ([]ExternalRange, error) {
+	content, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read YAML: %v", err)
+	}
+
+	var rangeFile struct {
+		Ranges []ExternalRange `yaml:"ranges"`
+	}
+
+	if err := yaml.UnmarshalStrict(content, &rangeFile); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %v", err)
+	}
+
+	if len(rangeFile.Ranges) == 0 {
+		return nil, fmt.Errorf("no ranges found")
+	}
+
+	return rangeFile.Ranges, nil
+}
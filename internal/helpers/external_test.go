@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadExternalRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		ranges  []ExternalRange
+		err     string
+	}{
+		{
+			name:    "CSV columns in any order and case",
+			file:    "ranges.csv",
+			content: "Source,CIDR,Name\n OnPrem ,10.1.0.1/16,dc1\n",
+			ranges:  []ExternalRange{{CIDR: "10.1.0.0/16", Source: SourceOnPrem, Name: "dc1"}},
+		},
+		{
+			name:    "CSV /32 and /0",
+			file:    "ranges.csv",
+			content: "cidr,source\n192.168.1.1/32,vpn\n0.0.0.0/0,partner\n",
+			ranges:  []ExternalRange{{CIDR: "192.168.1.1/32", Source: SourceVPN}, {CIDR: "0.0.0.0/0", Source: SourcePartner}},
+		},
+		{
+			name:    "CSV IPv6 range",
+			file:    "ranges.csv",
+			content: "cidr,source\n2001:db8::1/32,partner\n",
+			ranges:  []ExternalRange{{CIDR: "2001:db8::/32", Source: SourcePartner}},
+		},
+		{
+			name:    "CSV missing source column",
+			file:    "ranges.csv",
+			content: "cidr,name\n10.0.0.0/8,dc1\n",
+			err:     "CSV header is missing the source column",
+		},
+		{
+			name:    "CSV header only",
+			file:    "ranges.csv",
+			content: "cidr,source\n",
+			err:     "CSV must have a header row and at least one range",
+		},
+		{
+			name:    "CSV row with extra fields",
+			file:    "ranges.csv",
+			content: "cidr,source\n10.0.0.0/8,onprem,extra\n",
+			err:     "failed to parse CSV",
+		},
+		{
+			name:    "CSV unsupported source",
+			file:    "ranges.csv",
+			content: "cidr,source\n10.0.0.0/8,cloud\n",
+			err:     `range 1: unsupported source "cloud"`,
+		},
+		{
+			name:    "CSV invalid CIDR",
+			file:    "ranges.csv",
+			content: "cidr,source\n10.0.0.0/8,onprem\n10.0.0.0/33,onprem\n",
+			err:     "range 2: error parsing CIDR",
+		},
+		{
+			name:    "YAML",
+			file:    "ranges.yml",
+			content: "ranges:\n  - cidr: 172.16.0.0/12\n    source: partner\n    description: Partner network\n",
+			ranges:  []ExternalRange{{CIDR: "172.16.0.0/12", Source: SourcePartner, Description: "Partner network"}},
+		},
+		{
+			name:    "YAML unknown field",
+			file:    "ranges.yaml",
+			content: "ranges:\n  - cidr: 172.16.0.0/12\n    source: partner\n    owner: me\n",
+			err:     "failed to parse YAML",
+		},
+		{
+			name:    "YAML malformed",
+			file:    "ranges.yaml",
+			content: "ranges:\n  - cidr: [172.16.0.0/12\n",
+			err:     "failed to parse YAML",
+		},
+		{
+			name:    "YAML without ranges",
+			file:    "ranges.yaml",
+			content: "ranges: []\n",
+			err:     "no ranges found",
+		},
+		{
+			name:    "unsupported file type",
+			file:    "ranges.txt",
+			content: "10.0.0.0/8\n",
+			err:     "unsupported file type .txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			ranges, err := LoadExternalRanges(path)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ranges, tt.ranges) {
+				t.Errorf("ranges %v, want %v", ranges, tt.ranges)
+			}
+		})
+	}
+}
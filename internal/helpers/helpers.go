@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/big"
 	"net"
 	"os"
+	"sort"
 	"text/template"
 
+	"github.com/asafdavid23/vpc-cidr-manager/templates"
 	"gopkg.in/yaml.v2"
 )
 
@@ -16,13 +19,40 @@ type CIDRReservation struct {
 	CIDR string `json:"cidr"`
 }
 
+// Pool is a named supernet that CIDR reservations are allocated from.
+type Pool struct {
+	Name string `mapstructure:"name" json:"name" yaml:"name"`
+	CIDR string `mapstructure:"cidr" json:"cidr" yaml:"cidr"`
+}
+
+// ReservationRequest is a single CIDR to reserve as part of a batch. Either CIDR is set,
+// or BaseCIDR and PrefixSize are set and the CIDR is generated.
+type ReservationRequest struct {
+	CIDR       string `yaml:"cidr"`
+	BaseCIDR   string `yaml:"baseCidr"`
+	PrefixSize int    `yaml:"prefixSize"`
+	VpcID      string `yaml:"vpcId"`
+	VpcName    string `yaml:"vpcName"`
+}
+
 type IAMTemplateData struct {
-	RoleName  string
-	Principal string
+	RoleName           string
+	RolePath           string
+	Principal          string
+	ExternalID         string
+	RequireMFA         bool
+	MaxSessionDuration int
 }
 
 type DynamoDBTableTemplateData struct {
 	TableName string
+	TableOptions
+}
+
+// HubPolicyTemplateData renders the hub policy template, PolicyDocument is the policy as JSON.
+type HubPolicyTemplateData struct {
+	PolicyName     string
+	PolicyDocument string
 }
 
 func GenerateCIDR(existingCIDRs []string, baseCIDR string, prefixSize int) (string, error) {
@@ -48,6 +78,74 @@ func GenerateCIDR(existingCIDRs []string, baseCIDR string, prefixSize int) (stri
 	return "", fmt.Errorf("no available CIDR found")
 }
 
+// LoadReservationRequests loads a batch of reservation requests from a YAML file with a top level reservations list.
+func LoadReservationRequests(filePath string) ([]ReservationRequest, error) {
+	content, err := os.ReadFile(filePath)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+
+	var requestFile struct {
+		Reservations []ReservationRequest `yaml:"reservations"`
+	}
+
+	if err := yaml.UnmarshalStrict(content, &requestFile); err != nil {
+		return nil, fmt.Errorf("failed to parse reservation requests: %v", err)
+	}
+
+	if len(requestFile.Reservations) == 0 {
+		return nil, fmt.Errorf("no reservations found in %s", filePath)
+	}
+
+	return requestFile.Reservations, nil
+}
+
+// AllocateCIDRs generates a CIDR for every request that does not set one. Generated CIDRs
+// don't overlap the existing CIDRs, the explicitly requested CIDRs, or each other.
+// Requests are returned in their original order.
+func AllocateCIDRs(existingCIDRs []string, requests []ReservationRequest) ([]ReservationRequest, error) {
+	allocated := make([]ReservationRequest, len(requests))
+	copy(allocated, requests)
+
+	taken := append([]string{}, existingCIDRs...)
+	var pending []int
+
+	for i, request := range allocated {
+		if request.CIDR != "" {
+			if _, _, err := net.ParseCIDR(request.CIDR); err != nil {
+				return nil, fmt.Errorf("error parsing CIDR: %v", err)
+			}
+			taken = append(taken, request.CIDR)
+			continue
+		}
+
+		if request.BaseCIDR == "" || request.PrefixSize == 0 {
+			return nil, fmt.Errorf("reservation %d must set either cidr or baseCidr and prefixSize", i+1)
+		}
+
+		pending = append(pending, i)
+	}
+
+	// Allocate the largest blocks first so smaller ones don't fragment the base CIDR
+	sort.SliceStable(pending, func(a, b int) bool {
+		return allocated[pending[a]].PrefixSize < allocated[pending[b]].PrefixSize
+	})
+
+	for _, i := range pending {
+		cidr, err := GenerateCIDR(taken, allocated[i].BaseCIDR, allocated[i].PrefixSize)
+
+		if err != nil {
+			return nil, fmt.Errorf("reservation %d: %v", i+1, err)
+		}
+
+		allocated[i].CIDR = cidr
+		taken = append(taken, cidr)
+	}
+
+	return allocated, nil
+}
+
 func SplitCIDR(network *net.IPNet, prefixSize int) ([]*net.IPNet, error) {
 	var subnets []*net.IPNet
 	basePrefix, _ := network.Mask.Size()
@@ -60,66 +158,186 @@ func SplitCIDR(network *net.IPNet, prefixSize int) ([]*net.IPNet, error) {
 	numSubnets := 1 << (prefixSize - basePrefix)
 
 	for i := 0; i < numSubnets; i++ {
-		ip := network.IP.Mask(network.Mask)
+		subnets = append(subnets, nthSubnet(network, prefixSize, int64(i)))
+	}
 
-		for j := len(ip) - 1; j >= 0; j-- {
-			ip[j] += byte(i >> (8 * (len(ip) - 1 - j)))
-		}
+	return subnets, nil
+}
 
-		subnet := &net.IPNet{
-			IP:   ip,
-			Mask: net.CIDRMask(prefixSize, 8*len(ip)),
-		}
+// nthSubnet returns the i-th subnet of the given prefix size inside network.
+func nthSubnet(network *net.IPNet, prefixSize int, i int64) *net.IPNet {
+	_, bits := network.Mask.Size()
+	base := new(big.Int).SetBytes(network.IP.Mask(network.Mask))
+	offset := new(big.Int).Lsh(big.NewInt(i), uint(bits-prefixSize))
 
-		subnets = append(subnets, subnet)
-	}
+	ip := make(net.IP, bits/8)
+	new(big.Int).Add(base, offset).FillBytes(ip)
 
-	return subnets, nil
+	return &net.IPNet{
+		IP:   ip,
+		Mask: net.CIDRMask(prefixSize, bits),
+	}
 }
 
 // isOverlapping checks if a CIDR overlaps with any CIDRs in a list.
 func isOverlapping(cidr *net.IPNet, existingCIDRs []string) bool {
-	for _, existingCIDR := range existingCIDRs {
-		_, existingNet, err := net.ParseCIDR(existingCIDR)
+	return overlapsAny(cidr, parseCIDRs(existingCIDRs))
+}
+
+// parseCIDRs parses a list of CIDRs, skipping the ones that are invalid.
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	var networks []*net.IPNet
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
 		if err != nil {
-			log.Printf("Skipping invalid CIDR %s: %v", existingCIDR, err)
+			log.Printf("Skipping invalid CIDR %s: %v", cidr, err)
 			continue
 		}
-		if cidr.Contains(existingNet.IP) || existingNet.Contains(cidr.IP) {
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// overlapsAny checks if a CIDR overlaps with any of the given networks.
+func overlapsAny(cidr *net.IPNet, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if CIDRsOverlap(cidr, network) {
 			return true
 		}
 	}
 	return false
 }
 
-// LoadAndRenderTemplate loads a CloudFormation template from a file, processes it with dynamic values, and returns the rendered template
-func LoadAndRenderIAMTemplate(templateFilePath string, data IAMTemplateData) (string, error) {
-	// Read the template file
-	cfnTemplate, err := os.ReadFile(templateFilePath)
+// CIDRsOverlap checks if two CIDRs overlap
+func CIDRsOverlap(cidr1, cidr2 *net.IPNet) bool {
+	return cidr1.Contains(cidr2.IP) || cidr2.Contains(cidr1.IP)
+}
+
+// ContainsCIDR checks if outer fully contains inner.
+func ContainsCIDR(outer, inner *net.IPNet) bool {
+	outerPrefix, outerBits := outer.Mask.Size()
+	innerPrefix, innerBits := inner.Mask.Size()
+
+	return outerBits == innerBits && outerPrefix <= innerPrefix && outer.Contains(inner.IP)
+}
+
+// FindContainingPool returns the smallest pool that fully contains cidr, or nil if no pool does.
+func FindContainingPool(pools []Pool, cidr string) (*Pool, error) {
+	_, network, err := net.ParseCIDR(cidr)
+
 	if err != nil {
-		return "", fmt.Errorf("failed to read template file: %v", err)
+		return nil, fmt.Errorf("error parsing CIDR: %v", err)
 	}
 
-	// Parse the template
-	tmpl, err := template.New("cfnTemplate").Parse(string(cfnTemplate))
+	var containing *Pool
+	containingPrefix := -1
+
+	for i := range pools {
+		_, poolNetwork, err := net.ParseCIDR(pools[i].CIDR)
+
+		if err != nil {
+			return nil, fmt.Errorf("error parsing pool %s CIDR: %v", pools[i].Name, err)
+		}
+
+		poolPrefix, _ := poolNetwork.Mask.Size()
+
+		if ContainsCIDR(poolNetwork, network) && poolPrefix > containingPrefix {
+			containing = &pools[i]
+			containingPrefix = poolPrefix
+		}
+	}
+
+	return containing, nil
+}
+
+// ClosestFreeCIDRs returns up to count free CIDRs of the same size as cidr inside pool, nearest to cidr first.
+func ClosestFreeCIDRs(existingCIDRs []string, pool string, cidr string, count int) ([]string, error) {
+	_, poolNetwork, err := net.ParseCIDR(pool)
+
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %v", err)
+		return nil, fmt.Errorf("error parsing pool CIDR: %v", err)
 	}
 
-	// Apply the data to the template
-	var renderedTemplate bytes.Buffer
-	err = tmpl.Execute(&renderedTemplate, data)
+	_, network, err := net.ParseCIDR(cidr)
+
 	if err != nil {
-		return "", fmt.Errorf("failed to execute template: %v", err)
+		return nil, fmt.Errorf("error parsing CIDR: %v", err)
 	}
 
-	return renderedTemplate.String(), nil
+	if !ContainsCIDR(poolNetwork, network) {
+		return nil, fmt.Errorf("CIDR %s is not inside pool %s", cidr, pool)
+	}
+
+	poolPrefix, bits := poolNetwork.Mask.Size()
+	prefixSize, _ := network.Mask.Size()
+
+	if prefixSize-poolPrefix > 62 {
+		return nil, fmt.Errorf("pool %s is too large to search for /%d blocks", pool, prefixSize)
+	}
+
+	// Index of the requested CIDR among the pool's subnets of the same size
+	offset := new(big.Int).Sub(new(big.Int).SetBytes(network.IP), new(big.Int).SetBytes(poolNetwork.IP))
+	index := new(big.Int).Rsh(offset, uint(bits-prefixSize)).Int64()
+	numSubnets := int64(1) << (prefixSize - poolPrefix)
+
+	existing := parseCIDRs(existingCIDRs)
+	var free []string
+
+	// Walk outwards from the requested CIDR so that the nearest blocks come first
+	for distance := int64(0); len(free) < count && (index-distance >= 0 || index+distance < numSubnets); distance++ {
+		candidates := []int64{index - distance}
+
+		if distance > 0 {
+			candidates = append(candidates, index+distance)
+		}
+
+		for _, candidate := range candidates {
+			if candidate < 0 || candidate >= numSubnets || len(free) == count {
+				continue
+			}
+
+			subnet := nthSubnet(poolNetwork, prefixSize, candidate)
+
+			if !overlapsAny(subnet, existing) {
+				free = append(free, subnet.String())
+			}
+		}
+	}
+
+	return free, nil
 }
 
-// LoadAndRenderTemplate loads a CloudFormation template from a file, processes it with dynamic values, and returns the rendered template
+// LoadAndRenderIAMTemplate loads the IAM role template, processes it with dynamic values, and returns the rendered template.
+// The built-in template is used unless templateFilePath is set.
+func LoadAndRenderIAMTemplate(templateFilePath string, data IAMTemplateData) (string, error) {
+	return loadAndRenderTemplate(templates.IAMRole, templateFilePath, data)
+}
+
+// LoadAndRenderCFNTemplate loads the DynamoDB table template, processes it with dynamic values, and returns the rendered template.
+// The built-in template is used unless templateFilePath is set.
 func LoadAndRenderCFNTemplate(templateFilePath string, data DynamoDBTableTemplateData) (string, error) {
+	return loadAndRenderTemplate(templates.DynamoDBTable, templateFilePath, data)
+}
+
+// RenderBuiltinTemplate renders the built-in template name, e.g. one of the Terraform templates.
+func RenderBuiltinTemplate(name string, data interface{}) (string, error) {
+	return loadAndRenderTemplate(name, "", data)
+}
+
+// loadAndRenderTemplate renders the file at templateFilePath, or the built-in template name when no file is given.
+func loadAndRenderTemplate(name string, templateFilePath string, data interface{}) (string, error) {
+	var cfnTemplate []byte
+	var err error
+
 	// Read the template file
-	cfnTemplate, err := os.ReadFile(templateFilePath)
+	if templateFilePath != "" {
+		cfnTemplate, err = os.ReadFile(templateFilePath)
+	} else {
+		cfnTemplate, err = templates.Read(name)
+	}
+
 	if err != nil {
 		return "", fmt.Errorf("failed to read template file: %v", err)
 	}
@@ -0,0 +1,235 @@
+package helpers
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClosestFreeCIDRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		pool     string
+		cidr     string
+		count    int
+		free     []string
+		err      string
+	}{
+		{
+			name:     "nearest blocks come first",
+			existing: []string{"10.0.2.0/24", "10.0.3.0/24"},
+			pool:     "10.0.0.0/16",
+			cidr:     "10.0.2.0/24",
+			count:    3,
+			free:     []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.4.0/24"},
+		},
+		{
+			name:  "a free CIDR is its own closest block",
+			pool:  "10.0.0.0/16",
+			cidr:  "10.0.2.0/24",
+			count: 1,
+			free:  []string{"10.0.2.0/24"},
+		},
+		{
+			name:     "full pool",
+			existing: []string{"10.0.0.0/24"},
+			pool:     "10.0.0.0/24",
+			cidr:     "10.0.0.0/26",
+			count:    2,
+		},
+		{
+			name:     "/32 blocks",
+			existing: []string{"10.0.0.2/32", "10.0.0.3/32"},
+			pool:     "10.0.0.0/30",
+			cidr:     "10.0.0.3/32",
+			count:    5,
+			free:     []string{"10.0.0.1/32", "10.0.0.0/32"},
+		},
+		{
+			name:     "/0 pool",
+			existing: []string{"128.0.0.0/1"},
+			pool:     "0.0.0.0/0",
+			cidr:     "128.0.0.0/1",
+			count:    2,
+			free:     []string{"0.0.0.0/1"},
+		},
+		{
+			name:  "IPv6 CIDR in an IPv4 pool",
+			pool:  "10.0.0.0/16",
+			cidr:  "2001:db8::/48",
+			count: 1,
+			err:   "is not inside pool",
+		},
+		{
+			name:  "CIDR outside the pool",
+			pool:  "10.0.0.0/16",
+			cidr:  "10.1.0.0/24",
+			count: 1,
+			err:   "is not inside pool",
+		},
+		{
+			name:  "pool too large to search",
+			pool:  "::/0",
+			cidr:  "2001:db8::/64",
+			count: 1,
+			err:   "too large",
+		},
+		{
+			name:  "invalid pool",
+			pool:  "10.0.0.0/33",
+			cidr:  "10.0.0.0/24",
+			count: 1,
+			err:   "error parsing pool CIDR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			free, err := ClosestFreeCIDRs(tt.existing, tt.pool, tt.cidr, tt.count)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(free, tt.free) {
+				t.Errorf("free %v, want %v", free, tt.free)
+			}
+		})
+	}
+}
+
+func TestNthSubnet(t *testing.T) {
+	tests := []struct {
+		name       string
+		network    string
+		prefixSize int
+		i          int64
+		subnet     string
+	}{
+		{name: "first subnet", network: "10.0.0.0/16", prefixSize: 24, i: 0, subnet: "10.0.0.0/24"},
+		{name: "later subnet", network: "10.0.0.0/16", prefixSize: 24, i: 5, subnet: "10.0.5.0/24"},
+		{name: "last /32 of /0", network: "0.0.0.0/0", prefixSize: 32, i: 1<<32 - 1, subnet: "255.255.255.255/32"},
+		{name: "same size", network: "10.0.0.0/16", prefixSize: 16, i: 0, subnet: "10.0.0.0/16"},
+		{name: "IPv6", network: "2001:db8::/32", prefixSize: 48, i: 1, subnet: "2001:db8:1::/48"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, network, err := net.ParseCIDR(tt.network)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if subnet := nthSubnet(network, tt.prefixSize, tt.i).String(); subnet != tt.subnet {
+				t.Errorf("subnet %s, want %s", subnet, tt.subnet)
+			}
+		})
+	}
+}
+
+func TestAllocateCIDRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		requests []ReservationRequest
+		cidrs    []string
+		err      string
+	}{
+		{
+			name:     "largest blocks are allocated first and order is kept",
+			existing: []string{"10.0.0.0/24"},
+			requests: []ReservationRequest{
+				{CIDR: "10.0.1.0/24"},
+				{BaseCIDR: "10.0.0.0/16", PrefixSize: 24},
+				{BaseCIDR: "10.0.0.0/16", PrefixSize: 20},
+			},
+			cidrs: []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.16.0/20"},
+		},
+		{
+			name:     "/32 blocks",
+			existing: []string{"10.0.0.0/32"},
+			requests: []ReservationRequest{
+				{BaseCIDR: "10.0.0.0/30", PrefixSize: 32},
+				{BaseCIDR: "10.0.0.0/30", PrefixSize: 32},
+			},
+			cidrs: []string{"10.0.0.1/32", "10.0.0.2/32"},
+		},
+		{
+			name:     "/0 base",
+			existing: []string{"0.0.0.0/1"},
+			requests: []ReservationRequest{
+				{BaseCIDR: "0.0.0.0/0", PrefixSize: 1},
+			},
+			cidrs: []string{"128.0.0.0/1"},
+		},
+		{
+			name: "full pool",
+			requests: []ReservationRequest{
+				{BaseCIDR: "10.0.0.0/24", PrefixSize: 25},
+				{BaseCIDR: "10.0.0.0/24", PrefixSize: 25},
+				{BaseCIDR: "10.0.0.0/24", PrefixSize: 25},
+			},
+			err: "reservation 3: no available CIDR found",
+		},
+		{
+			name: "prefix size not inside the base",
+			requests: []ReservationRequest{
+				{BaseCIDR: "10.0.0.0/24", PrefixSize: 24},
+			},
+			err: "prefix size must be greater",
+		},
+		{
+			name: "missing prefix size",
+			requests: []ReservationRequest{
+				{BaseCIDR: "10.0.0.0/16"},
+			},
+			err: "reservation 1 must set either cidr or baseCidr and prefixSize",
+		},
+		{
+			name: "invalid CIDR",
+			requests: []ReservationRequest{
+				{CIDR: "10.0.0.0/33"},
+			},
+			err: "error parsing CIDR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocated, err := AllocateCIDRs(tt.existing, tt.requests)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cidrs []string
+
+			for _, request := range allocated {
+				cidrs = append(cidrs, request.CIDR)
+			}
+
+			if !reflect.DeepEqual(cidrs, tt.cidrs) {
+				t.Errorf("cidrs %v, want %v", cidrs, tt.cidrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultRoleArnTemplate builds the ARN of the role assumed in spoke accounts.
+const DefaultRoleArnTemplate = "arn:{{.Partition}}:iam::{{.AccountID}}:role{{.RolePath}}{{.RoleName}}"
+
+// RoleArnData is the data available to a role ARN template.
+type RoleArnData struct {
+	Partition string
+	AccountID string
+	RolePath  string
+	RoleName  string
+}
+
+// NormalizeRolePath makes an IAM path start and end with a slash, so "earnix" becomes "/earnix/".
+func NormalizeRolePath(path string) string {
+	path = strings.Trim(path, "/")
+
+	if path == "" {
+		return "/"
+	}
+
+	return "/" + path + "/"
+}
+
+// RenderRoleArn renders a role ARN template. An empty template falls back to DefaultRoleArnTemplate.
+func RenderRoleArn(arnTemplate string, data RoleArnData) (string, error) {
+	if arnTemplate == "" {
+		arnTemplate = DefaultRoleArnTemplate
+	}
+
+	if data.AccountID == "" || data.RoleName == "" {
+		return "", fmt.Errorf("account ID and role name are required to build the role ARN")
+	}
+
+	data.RolePath = NormalizeRolePath(data.RolePath)
+
+	tmpl, err := template.New("roleArn").Option("missingkey=error").Parse(arnTemplate)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to parse role ARN template: %v", err)
+	}
+
+	var roleArn bytes.Buffer
+
+	if err := tmpl.Execute(&roleArn, data); err != nil {
+		return "", fmt.Errorf("failed to render role ARN template: %v", err)
+	}
+
+	return roleArn.String(), nil
+}
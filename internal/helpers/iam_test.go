@@ -0,0 +1,108 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRolePath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "empty", path: "", want: "/"},
+		{name: "root", path: "/", want: "/"},
+		{name: "no slashes", path: "earnix", want: "/earnix/"},
+		{name: "leading slash", path: "/earnix", want: "/earnix/"},
+		{name: "already normalized", path: "/earnix/", want: "/earnix/"},
+		{name: "nested", path: "earnix/network", want: "/earnix/network/"},
+		{name: "repeated slashes", path: "//earnix//", want: "/earnix/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if path := NormalizeRolePath(tt.path); path != tt.want {
+				t.Errorf("path %q, want %q", path, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderRoleArn(t *testing.T) {
+	data := RoleArnData{
+		Partition: "aws",
+		AccountID: "123456789012",
+		RolePath:  "earnix",
+		RoleName:  "vpc-cidr-manager-role",
+	}
+
+	tests := []struct {
+		name        string
+		arnTemplate string
+		data        RoleArnData
+		arn         string
+		err         string
+	}{
+		{
+			name: "default template",
+			data: data,
+			arn:  "arn:aws:iam::123456789012:role/earnix/vpc-cidr-manager-role",
+		},
+		{
+			name: "root path",
+			data: RoleArnData{Partition: "aws-cn", AccountID: "123456789012", RoleName: "vpc-cidr-manager-role"},
+			arn:  "arn:aws-cn:iam::123456789012:role/vpc-cidr-manager-role",
+		},
+		{
+			name:        "custom template",
+			arnTemplate: "arn:{{.Partition}}:iam::{{.AccountID}}:role/custom/{{.RoleName}}",
+			data:        data,
+			arn:         "arn:aws:iam::123456789012:role/custom/vpc-cidr-manager-role",
+		},
+		{
+			name: "missing account ID",
+			data: RoleArnData{Partition: "aws", RoleName: "vpc-cidr-manager-role"},
+			err:  "account ID and role name are required",
+		},
+		{
+			name: "missing role name",
+			data: RoleArnData{Partition: "aws", AccountID: "123456789012"},
+			err:  "account ID and role name are required",
+		},
+		{
+			name:        "unclosed action",
+			arnTemplate: "arn:{{.Partition}:iam::{{.AccountID}}:role{{.RolePath}}{{.RoleName}}",
+			data:        data,
+			err:         "failed to parse role ARN template",
+		},
+		{
+			name:        "unknown field",
+			arnTemplate: "arn:{{.Partition}}:iam::{{.Account}}:role{{.RolePath}}{{.RoleName}}",
+			data:        data,
+			err:         "failed to render role ARN template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arn, err := RenderRoleArn(tt.arnTemplate, tt.data)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if arn != tt.arn {
+				t.Errorf("arn %s, want %s", arn, tt.arn)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Features the hub policy can grant permissions for.
+const (
+	FeatureReservations = "reservations"
+	FeatureCrossAccount = "cross-account"
+	FeatureImport       = "import"
+	FeatureRouting      = "routing"
+	FeatureTagging      = "tagging"
+	FeatureIPAM         = "ipam"
+	FeatureIaac         = "iaac"
+	FeatureBackup       = "backup"
+)
+
+// HubPolicyFeatures are all the features of the hub policy, reservations is always included.
+var HubPolicyFeatures = []string{FeatureReservations, FeatureCrossAccount, FeatureImport, FeatureRouting, FeatureTagging, FeatureIPAM, FeatureIaac, FeatureBackup}
+
+// PolicyDocument is an IAM policy document.
+type PolicyDocument struct {
+	Version   string            `json:"Version" yaml:"Version"`
+	Statement []PolicyStatement `json:"Statement" yaml:"Statement"`
+}
+
+// PolicyStatement is a statement of an IAM policy document.
+type PolicyStatement struct {
+	Sid      string   `json:"Sid" yaml:"Sid"`
+	Effect   string   `json:"Effect" yaml:"Effect"`
+	Action   []string `json:"Action" yaml:"Action"`
+	Resource []string `json:"Resource" yaml:"Resource"`
+}
+
+// HubPolicyData scopes the hub policy to the resources the CLI manages.
+type HubPolicyData struct {
+	Partition string
+	Region    string
+	AccountID string
+	TableName string
+
+	// ReplicaRegions are the regions of a global table, the table is accessible in each of them.
+	ReplicaRegions []string
+
+	// RoleArns are the spoke roles the CLI assumes.
+	RoleArns []string
+
+	// StackNames and StackSetName are the CloudFormation stacks created by the iaac commands.
+	StackNames   []string
+	StackSetName string
+
+	// KMSKeyArn is the customer managed key that encrypts the table, if any.
+	KMSKeyArn string
+
+	// BackupBucket and BackupPrefix are where backups are exported to and restored from.
+	BackupBucket string
+	BackupPrefix string
+}
+
+// HubPolicy builds the least-privilege policy for the identity running the CLI with the given features.
+// sts:GetCallerIdentity needs no permission, so it is never part of the policy.
+func HubPolicy(features []string, data HubPolicyData) (PolicyDocument, error) {
+	if data.Partition == "" || data.Region == "" || data.AccountID == "" || data.TableName == "" {
+		return PolicyDocument{}, fmt.Errorf("partition, region, account ID and table name are required to build the policy")
+	}
+
+	enabled := map[string]bool{FeatureReservations: true}
+
+	for _, feature := range features {
+		if !isHubPolicyFeature(feature) {
+			return PolicyDocument{}, fmt.Errorf("unknown feature %s, expected one of %s", feature, strings.Join(HubPolicyFeatures, ", "))
+		}
+
+		enabled[feature] = true
+	}
+
+	var tableArns []string
+
+	for _, region := range withRegion(data.ReplicaRegions, data.Region) {
+		tableArns = append(tableArns, fmt.Sprintf("arn:%s:dynamodb:%s:%s:table/%s", data.Partition, region, data.AccountID, data.TableName))
+	}
+
+	policy := PolicyDocument{Version: "2012-10-17"}
+
+	policy.Statement = append(policy.Statement, PolicyStatement{
+		Sid:    "Reservations",
+		Effect: "Allow",
+		Action: []string{
+			"dynamodb:ConditionCheckItem",
+			"dynamodb:DeleteItem",
+			"dynamodb:DescribeTable",
+			"dynamodb:GetItem",
+			"dynamodb:PutItem",
+			"dynamodb:Scan",
+			"dynamodb:UpdateItem",
+		},
+		Resource: tableArns,
+	})
+
+	if enabled[FeatureCrossAccount] {
+		if len(data.RoleArns) == 0 {
+			return PolicyDocument{}, fmt.Errorf("the %s feature requires at least one role ARN", FeatureCrossAccount)
+		}
+
+		policy.Statement = append(policy.Statement, PolicyStatement{
+			Sid:      "AssumeSpokeRoles",
+			Effect:   "Allow",
+			Action:   []string{"sts:AssumeRole"},
+			Resource: data.RoleArns,
+		})
+	}
+
+	// EC2 describe and IPAM read calls do not support resource-level permissions
+	var describeActions []string
+
+	if enabled[FeatureImport] || enabled[FeatureTagging] {
+		describeActions = append(describeActions, "ec2:DescribeVpcs", "ec2:DescribeSubnets")
+	}
+
+	if enabled[FeatureRouting] {
+		describeActions = append(describeActions, "ec2:DescribeVpcs", "ec2:DescribeTransitGatewayAttachments", "ec2:SearchTransitGatewayRoutes", "ec2:DescribeVpcPeeringConnections")
+	}
+
+	if enabled[FeatureIPAM] {
+		describeActions = append(describeActions, "ec2:DescribeIpamPools", "ec2:GetIpamPoolAllocations", "ec2:GetIpamPoolCidrs", "ec2:GetIpamDiscoveredResourceCidrs")
+	}
+
+	if len(describeActions) > 0 {
+		policy.Statement = append(policy.Statement, PolicyStatement{
+			Sid:      "DescribeNetworking",
+			Effect:   "Allow",
+			Action:   uniqueSorted(describeActions),
+			Resource: []string{"*"},
+		})
+	}
+
+	if enabled[FeatureIPAM] {
+		policy.Statement = append(policy.Statement, PolicyStatement{
+			Sid:      "AllocateIpamPools",
+			Effect:   "Allow",
+			Action:   []string{"ec2:AllocateIpamPoolCidr"},
+			Resource: []string{fmt.Sprintf("arn:%s:ec2::%s:ipam-pool/*", data.Partition, data.AccountID)},
+		})
+	}
+
+	if enabled[FeatureTagging] {
+		policy.Statement = append(policy.Statement, PolicyStatement{
+			Sid:      "TagVpcs",
+			Effect:   "Allow",
+			Action:   []string{"ec2:CreateTags"},
+			Resource: []string{fmt.Sprintf("arn:%s:ec2:%s:%s:vpc/*", data.Partition, data.Region, data.AccountID)},
+		})
+	}
+
+	if enabled[FeatureIaac] {
+		policy.Statement = append(policy.Statement, iaacStatements(data, tableArns)...)
+	}
+
+	if enabled[FeatureBackup] {
+		if data.BackupBucket == "" {
+			return PolicyDocument{}, fmt.Errorf("the %s feature requires the S3 location of the backups", FeatureBackup)
+		}
+
+		policy.Statement = append(policy.Statement, PolicyStatement{
+			Sid:      "ExportAndRestoreBackups",
+			Effect:   "Allow",
+			Action:   []string{"s3:GetObject", "s3:GetObjectVersion", "s3:PutObject"},
+			Resource: []string{fmt.Sprintf("arn:%s:s3:::%s/%s*", data.Partition, data.BackupBucket, data.BackupPrefix)},
+		})
+	}
+
+	return policy, nil
+}
+
+// iaacStatements allow the iaac commands to manage their stacks, and CloudFormation to create the
+// table and spoke role with the caller's permissions.
+func iaacStatements(data HubPolicyData, tableArns []string) []PolicyStatement {
+	var stackArns []string
+
+	for _, stackName := range data.StackNames {
+		stackArns = append(stackArns, fmt.Sprintf("arn:%s:cloudformation:%s:%s:stack/%s/*", data.Partition, data.Region, data.AccountID, stackName))
+	}
+
+	statements := []PolicyStatement{
+		{
+			Sid:    "ManageStacks",
+			Effect: "Allow",
+			Action: []string{
+				"cloudformation:CancelUpdateStack",
+				"cloudformation:ContinueUpdateRollback",
+				"cloudformation:CreateChangeSet",
+				"cloudformation:CreateStack",
+				"cloudformation:DeleteChangeSet",
+				"cloudformation:DeleteStack",
+				"cloudformation:DescribeChangeSet",
+				"cloudformation:DescribeStackEvents",
+				"cloudformation:DescribeStacks",
+				"cloudformation:ExecuteChangeSet",
+			},
+			Resource: stackArns,
+		},
+		{
+			Sid:      "ValidateTemplates",
+			Effect:   "Allow",
+			Action:   []string{"cloudformation:ValidateTemplate"},
+			Resource: []string{"*"},
+		},
+		{
+			Sid:    "ManageTable",
+			Effect: "Allow",
+			Action: []string{
+				"dynamodb:CreateTable",
+				"dynamodb:CreateTableReplica",
+				"dynamodb:DeleteTable",
+				"dynamodb:DeleteTableReplica",
+				"dynamodb:DescribeContinuousBackups",
+				"dynamodb:DescribeTable",
+				"dynamodb:ListTagsOfResource",
+				"dynamodb:TagResource",
+				"dynamodb:UntagResource",
+				"dynamodb:UpdateContinuousBackups",
+				"dynamodb:UpdateTable",
+			},
+			Resource: tableArns,
+		},
+		{
+			Sid:    "ManageSpokeRole",
+			Effect: "Allow",
+			Action: []string{
+				"iam:CreateRole",
+				"iam:DeleteRole",
+				"iam:DeleteRolePolicy",
+				"iam:GetRole",
+				"iam:GetRolePolicy",
+				"iam:PutRolePolicy",
+				"iam:UpdateAssumeRolePolicy",
+				"iam:UpdateRole",
+			},
+			Resource: []string{fmt.Sprintf("arn:%s:iam::%s:role/*", data.Partition, data.AccountID)},
+		},
+		{
+			Sid:    "ManageHubPolicy",
+			Effect: "Allow",
+			Action: []string{
+				"iam:CreatePolicy",
+				"iam:CreatePolicyVersion",
+				"iam:DeletePolicy",
+				"iam:DeletePolicyVersion",
+				"iam:GetPolicy",
+				"iam:GetPolicyVersion",
+				"iam:ListPolicyVersions",
+			},
+			Resource: []string{fmt.Sprintf("arn:%s:iam::%s:policy/*", data.Partition, data.AccountID)},
+		},
+	}
+
+	// DynamoDB creates a grant on the key when the table is created with a customer managed key
+	if data.KMSKeyArn != "" {
+		statements = append(statements, PolicyStatement{
+			Sid:      "UseTableKey",
+			Effect:   "Allow",
+			Action:   []string{"kms:CreateGrant", "kms:DescribeKey"},
+			Resource: []string{data.KMSKeyArn},
+		})
+	}
+
+	if data.StackSetName != "" {
+		statements = append(statements, PolicyStatement{
+			Sid:    "ManageStackSet",
+			Effect: "Allow",
+			Action: []string{
+				"cloudformation:CreateStackInstances",
+				"cloudformation:CreateStackSet",
+				"cloudformation:DescribeStackSet",
+				"cloudformation:DescribeStackSetOperation",
+				"cloudformation:ListStackInstances",
+				"cloudformation:ListStackSetOperationResults",
+				"cloudformation:UpdateStackSet",
+			},
+			Resource: []string{
+				fmt.Sprintf("arn:%s:cloudformation:*:%s:stackset/%s:*", data.Partition, data.AccountID, data.StackSetName),
+				fmt.Sprintf("arn:%s:cloudformation:*:*:stackset-target/*", data.Partition),
+				fmt.Sprintf("arn:%s:cloudformation:*::type/resource/AWS-IAM-Role", data.Partition),
+			},
+		})
+	}
+
+	return statements
+}
+
+func isHubPolicyFeature(feature string) bool {
+	for _, f := range HubPolicyFeatures {
+		if f == feature {
+			return true
+		}
+	}
+
+	return false
+}
+
+func uniqueSorted(values []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+
+	sort.Strings(unique)
+
+	return unique
+}
@@ -0,0 +1,113 @@
+package helpers
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubnetTier is a group of subnets of the same size that is repeated in every availability zone.
+type SubnetTier struct {
+	Name       string
+	PrefixSize int
+}
+
+// PlannedSubnet is a single subnet in a subnet layout.
+type PlannedSubnet struct {
+	Tier             string `json:"tier" yaml:"tier"`
+	AvailabilityZone string `json:"availabilityZone" yaml:"availabilityZone"`
+	CIDR             string `json:"cidr" yaml:"cidr"`
+}
+
+// ParseSubnetTiers parses a tier spec like "public:/24,private:/19,db:/24".
+func ParseSubnetTiers(spec string) ([]SubnetTier, error) {
+	var tiers []SubnetTier
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(spec, ",") {
+		name, size, found := strings.Cut(strings.TrimSpace(part), ":")
+
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid tier %q, expected name:/prefix", part)
+		}
+
+		prefixSize, err := strconv.Atoi(strings.TrimPrefix(size, "/"))
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix size for tier %s: %v", name, err)
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("tier %s is defined more than once", name)
+		}
+
+		seen[name] = true
+		tiers = append(tiers, SubnetTier{Name: name, PrefixSize: prefixSize})
+	}
+
+	return tiers, nil
+}
+
+// PlanSubnets carves vpcCIDR into one subnet per tier per availability zone. The largest
+// subnets are allocated first so that every subnet stays aligned and none of them overlap.
+// Subnets are returned grouped by tier in the order the tiers were given.
+func PlanSubnets(vpcCIDR string, availabilityZones []string, tiers []SubnetTier) ([]PlannedSubnet, error) {
+	_, vpcNetwork, err := net.ParseCIDR(vpcCIDR)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing VPC CIDR: %v", err)
+	}
+
+	if len(availabilityZones) == 0 || len(tiers) == 0 {
+		return nil, fmt.Errorf("at least one availability zone and one tier are required")
+	}
+
+	vpcPrefix, bits := vpcNetwork.Mask.Size()
+
+	for _, tier := range tiers {
+		if tier.PrefixSize <= vpcPrefix || tier.PrefixSize > bits {
+			return nil, fmt.Errorf("tier %s prefix size /%d does not fit in %s", tier.Name, tier.PrefixSize, vpcNetwork.String())
+		}
+	}
+
+	order := make([]int, len(tiers))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return tiers[order[a]].PrefixSize < tiers[order[b]].PrefixSize
+	})
+
+	var taken []string
+	allocated := make([][]PlannedSubnet, len(tiers))
+
+	for _, i := range order {
+		tier := tiers[i]
+
+		for _, az := range availabilityZones {
+			cidr, err := GenerateCIDR(taken, vpcNetwork.String(), tier.PrefixSize)
+
+			if err != nil {
+				return nil, fmt.Errorf("tier %s in %s: %v", tier.Name, az, err)
+			}
+
+			taken = append(taken, cidr)
+			allocated[i] = append(allocated[i], PlannedSubnet{
+				Tier:             tier.Name,
+				AvailabilityZone: az,
+				CIDR:             cidr,
+			})
+		}
+	}
+
+	var subnets []PlannedSubnet
+
+	for _, tierSubnets := range allocated {
+		subnets = append(subnets, tierSubnets...)
+	}
+
+	return subnets, nil
+}
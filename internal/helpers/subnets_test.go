@@ -0,0 +1,163 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSubnetTiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		tiers []SubnetTier
+		err   string
+	}{
+		{
+			name:  "tiers in order",
+			spec:  "public:/24,private:/19, db:/24",
+			tiers: []SubnetTier{{Name: "public", PrefixSize: 24}, {Name: "private", PrefixSize: 19}, {Name: "db", PrefixSize: 24}},
+		},
+		{
+			name:  "prefix without a slash",
+			spec:  "public:24",
+			tiers: []SubnetTier{{Name: "public", PrefixSize: 24}},
+		},
+		{name: "empty spec", spec: "", err: "invalid tier"},
+		{name: "missing prefix", spec: "public", err: "invalid tier"},
+		{name: "missing name", spec: ":/24", err: "invalid tier"},
+		{name: "invalid prefix", spec: "public:/x", err: "invalid prefix size for tier public"},
+		{name: "duplicate tier", spec: "public:/24,public:/25", err: "tier public is defined more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers, err := ParseSubnetTiers(tt.spec)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tiers, tt.tiers) {
+				t.Errorf("tiers %v, want %v", tiers, tt.tiers)
+			}
+		})
+	}
+}
+
+func TestPlanSubnets(t *testing.T) {
+	tests := []struct {
+		name    string
+		vpcCIDR string
+		azs     []string
+		tiers   []SubnetTier
+		subnets []PlannedSubnet
+		err     string
+	}{
+		{
+			name:    "largest tiers are allocated first and grouped by tier",
+			vpcCIDR: "10.0.0.0/16",
+			azs:     []string{"a", "b"},
+			tiers:   []SubnetTier{{Name: "public", PrefixSize: 24}, {Name: "private", PrefixSize: 19}},
+			subnets: []PlannedSubnet{
+				{Tier: "public", AvailabilityZone: "a", CIDR: "10.0.64.0/24"},
+				{Tier: "public", AvailabilityZone: "b", CIDR: "10.0.65.0/24"},
+				{Tier: "private", AvailabilityZone: "a", CIDR: "10.0.0.0/19"},
+				{Tier: "private", AvailabilityZone: "b", CIDR: "10.0.32.0/19"},
+			},
+		},
+		{
+			name:    "/32 subnets",
+			vpcCIDR: "10.0.0.0/31",
+			azs:     []string{"a", "b"},
+			tiers:   []SubnetTier{{Name: "host", PrefixSize: 32}},
+			subnets: []PlannedSubnet{
+				{Tier: "host", AvailabilityZone: "a", CIDR: "10.0.0.0/32"},
+				{Tier: "host", AvailabilityZone: "b", CIDR: "10.0.0.1/32"},
+			},
+		},
+		{
+			name:    "/0 VPC",
+			vpcCIDR: "0.0.0.0/0",
+			azs:     []string{"a"},
+			tiers:   []SubnetTier{{Name: "half", PrefixSize: 1}},
+			subnets: []PlannedSubnet{
+				{Tier: "half", AvailabilityZone: "a", CIDR: "0.0.0.0/1"},
+			},
+		},
+		{
+			name:    "IPv6",
+			vpcCIDR: "2001:db8::/56",
+			azs:     []string{"a", "b"},
+			tiers:   []SubnetTier{{Name: "public", PrefixSize: 64}},
+			subnets: []PlannedSubnet{
+				{Tier: "public", AvailabilityZone: "a", CIDR: "2001:db8::/64"},
+				{Tier: "public", AvailabilityZone: "b", CIDR: "2001:db8:0:1::/64"},
+			},
+		},
+		{
+			name:    "full VPC",
+			vpcCIDR: "10.0.0.0/24",
+			azs:     []string{"a", "b", "c"},
+			tiers:   []SubnetTier{{Name: "private", PrefixSize: 25}},
+			err:     "tier private in c: no available CIDR found",
+		},
+		{
+			name:    "prefix size longer than an address",
+			vpcCIDR: "10.0.0.0/24",
+			azs:     []string{"a"},
+			tiers:   []SubnetTier{{Name: "private", PrefixSize: 33}},
+			err:     "tier private prefix size /33 does not fit in 10.0.0.0/24",
+		},
+		{
+			name:    "prefix size as large as the VPC",
+			vpcCIDR: "10.0.0.0/24",
+			azs:     []string{"a"},
+			tiers:   []SubnetTier{{Name: "private", PrefixSize: 24}},
+			err:     "does not fit",
+		},
+		{
+			name:    "no tiers",
+			vpcCIDR: "10.0.0.0/24",
+			azs:     []string{"a"},
+			err:     "at least one availability zone and one tier are required",
+		},
+		{
+			name:    "invalid VPC CIDR",
+			vpcCIDR: "10.0.0.0",
+			azs:     []string{"a"},
+			tiers:   []SubnetTier{{Name: "private", PrefixSize: 25}},
+			err:     "error parsing VPC CIDR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subnets, err := PlanSubnets(tt.vpcCIDR, tt.azs, tt.tiers)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(subnets, tt.subnets) {
+				t.Errorf("subnets %v, want %v", subnets, tt.subnets)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Billing modes of the reservations table.
+const (
+	BillingModeProvisioned   = "PROVISIONED"
+	BillingModePayPerRequest = "PAY_PER_REQUEST"
+)
+
+// TableOptions configure the reservations table, both when it is created with the SDK and when it
+// is rendered into a template.
+type TableOptions struct {
+	BillingMode string
+
+	// ReadCapacity and WriteCapacity only apply to provisioned billing.
+	ReadCapacity  int64
+	WriteCapacity int64
+
+	PointInTimeRecovery bool
+	DeletionProtection  bool
+
+	// KMSKeyArn encrypts the table with a customer managed key, empty keeps the AWS owned key.
+	KMSKeyArn string
+
+	Tags map[string]string
+
+	// Region is the region the table is created in. ReplicaRegions turn the table into a global table
+	// replicated to every listed region, they always include Region.
+	Region         string
+	ReplicaRegions []string
+}
+
+// Global reports whether the table is replicated to other regions.
+func (o TableOptions) Global() bool {
+	return len(o.ReplicaRegions) > 1
+}
+
+// Provisioned reports whether the table uses provisioned capacity.
+func (o TableOptions) Provisioned() bool {
+	return o.BillingMode == BillingModeProvisioned
+}
+
+// Normalize upper-cases the billing mode, accepting "on-demand" for pay-per-request, and validates the options.
+func (o TableOptions) Normalize() (TableOptions, error) {
+	switch strings.ToUpper(strings.ReplaceAll(o.BillingMode, "-", "_")) {
+	case "", BillingModeProvisioned:
+		o.BillingMode = BillingModeProvisioned
+	case BillingModePayPerRequest, "ON_DEMAND":
+		o.BillingMode = BillingModePayPerRequest
+	default:
+		return o, fmt.Errorf("unsupported billing mode %s, expected %s or %s", o.BillingMode, BillingModeProvisioned, BillingModePayPerRequest)
+	}
+
+	if o.Provisioned() && (o.ReadCapacity < 1 || o.WriteCapacity < 1) {
+		return o, fmt.Errorf("provisioned billing requires read and write capacity of at least 1")
+	}
+
+	if o.KMSKeyArn != "" && !strings.HasPrefix(o.KMSKeyArn, "arn:") {
+		return o, fmt.Errorf("KMS key %s must be a key ARN", o.KMSKeyArn)
+	}
+
+	if len(o.ReplicaRegions) > 0 {
+		o.ReplicaRegions = withRegion(o.ReplicaRegions, o.Region)
+	}
+
+	// KMS keys are regional and replicas of a provisioned global table need autoscaling settings per
+	// replica, neither fits the single set of options the templates and the SDK share
+	if o.Global() {
+		if o.Provisioned() {
+			return o, fmt.Errorf("global tables require %s billing", BillingModePayPerRequest)
+		}
+
+		if o.KMSKeyArn != "" {
+			return o, fmt.Errorf("a KMS key ARN can not be used with replica regions, keys are regional")
+		}
+	}
+
+	return o, nil
+}
+
+// withRegion returns regions with region first and without duplicates.
+func withRegion(regions []string, region string) []string {
+	result := []string{region}
+
+	for _, r := range regions {
+		if r != "" && r != region && !contains(result, r) {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
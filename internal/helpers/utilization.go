@@ -0,0 +1,250 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"net"
+	"sort"
+)
+
+// Utilization describes how much of a pool is consumed by reservations.
+type Utilization struct {
+	Pool               Pool           `json:"pool" yaml:"pool"`
+	TotalAddresses     uint64         `json:"totalAddresses" yaml:"totalAddresses"`
+	ReservedAddresses  uint64         `json:"reservedAddresses" yaml:"reservedAddresses"`
+	FreeAddresses      uint64         `json:"freeAddresses" yaml:"freeAddresses"`
+	UsedPercent        float64        `json:"usedPercent" yaml:"usedPercent"`
+	Reservations       int            `json:"reservations" yaml:"reservations"`
+	PrefixCounts       map[string]int `json:"prefixCounts" yaml:"prefixCounts"`
+	LargestFreeRange   uint64         `json:"largestFreeRange" yaml:"largestFreeRange"`
+	LargestFreeBlock   string         `json:"largestFreeBlock,omitempty" yaml:"largestFreeBlock,omitempty"`
+	FragmentationRatio float64        `json:"fragmentationRatio" yaml:"fragmentationRatio"`
+}
+
+// ipRange is an inclusive range of IPv4 addresses.
+type ipRange struct {
+	first uint64
+	last  uint64
+}
+
+func (r ipRange) size() uint64 {
+	return r.last - r.first + 1
+}
+
+// cidrToRange converts an IPv4 CIDR into the range of addresses it covers.
+func cidrToRange(network *net.IPNet) (ipRange, error) {
+	ip := network.IP.To4()
+	prefix, maskBits := network.Mask.Size()
+
+	if ip == nil || maskBits != 32 {
+		return ipRange{}, fmt.Errorf("only IPv4 CIDRs are supported: %s", network)
+	}
+
+	first := uint64(binary.BigEndian.Uint32(ip.Mask(network.Mask)))
+
+	return ipRange{first: first, last: first + (uint64(1) << (32 - prefix)) - 1}, nil
+}
+
+// rangeToCIDRs splits a range into the smallest list of aligned CIDR blocks that cover it exactly.
+func rangeToCIDRs(r ipRange) []*net.IPNet {
+	var cidrs []*net.IPNet
+
+	for first := r.first; first <= r.last; {
+		// The block is limited by the alignment of its first address and by the end of the range
+		hostBits := 32
+		if first != 0 {
+			hostBits = bits.TrailingZeros64(first)
+		}
+
+		for hostBits > 0 && first+(uint64(1)<<hostBits)-1 > r.last {
+			hostBits--
+		}
+
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, uint32(first))
+		cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(32-hostBits, 32)})
+
+		first += uint64(1) << hostBits
+	}
+
+	return cidrs
+}
+
+// mergeRanges sorts ranges and merges the ones that overlap or touch.
+func mergeRanges(ranges []ipRange) []ipRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].first < ranges[j].first
+	})
+
+	var merged []ipRange
+
+	for _, r := range ranges {
+		if len(merged) > 0 && r.first <= merged[len(merged)-1].last+1 {
+			if r.last > merged[len(merged)-1].last {
+				merged[len(merged)-1].last = r.last
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// freeRanges returns the parts of pool that are not covered by the reserved CIDRs,
+// along with the reserved CIDRs that overlap the pool.
+func freeRanges(pool *net.IPNet, reservedCIDRs []string) ([]ipRange, []*net.IPNet, error) {
+	poolRange, err := cidrToRange(pool)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reserved []ipRange
+	var inPool []*net.IPNet
+
+	for _, network := range parseCIDRs(reservedCIDRs) {
+		if !CIDRsOverlap(pool, network) {
+			continue
+		}
+
+		r, err := cidrToRange(network)
+
+		if err != nil {
+			continue
+		}
+
+		// Only count the part of the reservation that falls inside the pool
+		if r.first < poolRange.first {
+			r.first = poolRange.first
+		}
+
+		if r.last > poolRange.last {
+			r.last = poolRange.last
+		}
+
+		reserved = append(reserved, r)
+		inPool = append(inPool, network)
+	}
+
+	var free []ipRange
+	next := poolRange.first
+
+	for _, r := range mergeRanges(reserved) {
+		if r.first > next {
+			free = append(free, ipRange{first: next, last: r.first - 1})
+		}
+		next = r.last + 1
+	}
+
+	if next <= poolRange.last {
+		free = append(free, ipRange{first: next, last: poolRange.last})
+	}
+
+	return free, inPool, nil
+}
+
+// ComputeUtilization calculates the reserved and free address space of a pool.
+func ComputeUtilization(pool Pool, reservedCIDRs []string) (Utilization, error) {
+	_, poolNetwork, err := net.ParseCIDR(pool.CIDR)
+
+	if err != nil {
+		return Utilization{}, fmt.Errorf("error parsing pool %s CIDR: %v", pool.Name, err)
+	}
+
+	poolRange, err := cidrToRange(poolNetwork)
+
+	if err != nil {
+		return Utilization{}, err
+	}
+
+	free, inPool, err := freeRanges(poolNetwork, reservedCIDRs)
+
+	if err != nil {
+		return Utilization{}, err
+	}
+
+	utilization := Utilization{
+		Pool:           pool,
+		TotalAddresses: poolRange.size(),
+		Reservations:   len(inPool),
+		PrefixCounts:   map[string]int{},
+	}
+
+	for _, network := range inPool {
+		prefix, _ := network.Mask.Size()
+		utilization.PrefixCounts[fmt.Sprintf("/%d", prefix)]++
+	}
+
+	var largestBlock *net.IPNet
+
+	for _, r := range free {
+		utilization.FreeAddresses += r.size()
+
+		if r.size() > utilization.LargestFreeRange {
+			utilization.LargestFreeRange = r.size()
+		}
+
+		for _, block := range rangeToCIDRs(r) {
+			if largestBlock == nil || widerMask(block.Mask, largestBlock.Mask) {
+				largestBlock = block
+			}
+		}
+	}
+
+	utilization.ReservedAddresses = utilization.TotalAddresses - utilization.FreeAddresses
+	utilization.UsedPercent = 100 * float64(utilization.ReservedAddresses) / float64(utilization.TotalAddresses)
+
+	if largestBlock != nil {
+		utilization.LargestFreeBlock = largestBlock.String()
+	}
+
+	// 0 means all free space is one contiguous range, values close to 1 mean it is scattered
+	if utilization.FreeAddresses > 0 {
+		utilization.FragmentationRatio = 1 - float64(utilization.LargestFreeRange)/float64(utilization.FreeAddresses)
+	}
+
+	return utilization, nil
+}
+
+// widerMask reports whether mask a is shorter (covers more addresses) than mask b.
+func widerMask(a, b net.IPMask) bool {
+	aPrefix, _ := a.Size()
+	bPrefix, _ := b.Size()
+
+	return aPrefix < bPrefix
+}
+
+// FreeCIDRs returns the minimal set of aligned CIDR blocks inside within that are not covered
+// by the reserved CIDRs. When prefixSize is greater than 0, only blocks that can hold a
+// CIDR of that prefix size are returned.
+func FreeCIDRs(within string, reservedCIDRs []string, prefixSize int) ([]string, error) {
+	_, withinNetwork, err := net.ParseCIDR(within)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CIDR: %v", err)
+	}
+
+	free, _, err := freeRanges(withinNetwork, reservedCIDRs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []string
+
+	for _, r := range free {
+		for _, block := range rangeToCIDRs(r) {
+			prefix, _ := block.Mask.Size()
+
+			if prefixSize > 0 && prefix > prefixSize {
+				continue
+			}
+
+			blocks = append(blocks, block.String())
+		}
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,222 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeUtilization(t *testing.T) {
+	tests := []struct {
+		name        string
+		pool        string
+		reserved    []string
+		utilization Utilization
+		err         string
+	}{
+		{
+			name:     "reservations outside the pool are ignored",
+			pool:     "10.0.0.0/24",
+			reserved: []string{"10.0.0.0/26", "10.0.0.128/26", "192.168.0.0/16"},
+			utilization: Utilization{
+				TotalAddresses:     256,
+				ReservedAddresses:  128,
+				FreeAddresses:      128,
+				UsedPercent:        50,
+				Reservations:       2,
+				PrefixCounts:       map[string]int{"/26": 2},
+				LargestFreeRange:   64,
+				LargestFreeBlock:   "10.0.0.64/26",
+				FragmentationRatio: 0.5,
+			},
+		},
+		{
+			name:     "full pool",
+			pool:     "10.0.0.0/24",
+			reserved: []string{"10.0.0.0/16"},
+			utilization: Utilization{
+				TotalAddresses:    256,
+				ReservedAddresses: 256,
+				UsedPercent:       100,
+				Reservations:      1,
+				PrefixCounts:      map[string]int{"/16": 1},
+			},
+		},
+		{
+			name: "/32 pool",
+			pool: "10.0.0.1/32",
+			utilization: Utilization{
+				TotalAddresses:   1,
+				FreeAddresses:    1,
+				PrefixCounts:     map[string]int{},
+				LargestFreeRange: 1,
+				LargestFreeBlock: "10.0.0.1/32",
+			},
+		},
+		{
+			name:     "/0 pool",
+			pool:     "0.0.0.0/0",
+			reserved: []string{"0.0.0.0/1"},
+			utilization: Utilization{
+				TotalAddresses:    1 << 32,
+				ReservedAddresses: 1 << 31,
+				FreeAddresses:     1 << 31,
+				UsedPercent:       50,
+				Reservations:      1,
+				PrefixCounts:      map[string]int{"/1": 1},
+				LargestFreeRange:  1 << 31,
+				LargestFreeBlock:  "128.0.0.0/1",
+			},
+		},
+		{
+			name:     "IPv6 reservations are ignored",
+			pool:     "10.0.0.0/31",
+			reserved: []string{"2001:db8::/32"},
+			utilization: Utilization{
+				TotalAddresses:   2,
+				FreeAddresses:    2,
+				PrefixCounts:     map[string]int{},
+				LargestFreeRange: 2,
+				LargestFreeBlock: "10.0.0.0/31",
+			},
+		},
+		{
+			name: "IPv6 pool",
+			pool: "2001:db8::/32",
+			err:  "only IPv4 CIDRs are supported",
+		},
+		{
+			name: "invalid pool",
+			pool: "10.0.0.0",
+			err:  "error parsing pool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := Pool{Name: "test", CIDR: tt.pool}
+			utilization, err := ComputeUtilization(pool, tt.reserved)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.utilization.Pool = pool
+
+			if !reflect.DeepEqual(utilization, tt.utilization) {
+				t.Errorf("utilization %+v, want %+v", utilization, tt.utilization)
+			}
+		})
+	}
+}
+
+func TestFreeCIDRs(t *testing.T) {
+	tests := []struct {
+		name       string
+		within     string
+		reserved   []string
+		prefixSize int
+		free       []string
+		err        string
+	}{
+		{
+			name:     "blocks around a reservation",
+			within:   "10.0.0.0/24",
+			reserved: []string{"10.0.0.64/26"},
+			free:     []string{"10.0.0.0/26", "10.0.0.128/25"},
+		},
+		{
+			name:       "blocks too small for the prefix size are left out",
+			within:     "10.0.0.0/24",
+			reserved:   []string{"10.0.0.64/26"},
+			prefixSize: 25,
+			free:       []string{"10.0.0.128/25"},
+		},
+		{
+			name:     "full range",
+			within:   "10.0.0.0/24",
+			reserved: []string{"10.0.0.0/25", "10.0.0.128/25"},
+		},
+		{
+			name:     "/32 gaps",
+			within:   "10.0.0.0/30",
+			reserved: []string{"10.0.0.1/32", "10.0.0.2/32"},
+			free:     []string{"10.0.0.0/32", "10.0.0.3/32"},
+		},
+		{
+			name:   "/0 range",
+			within: "0.0.0.0/0",
+			free:   []string{"0.0.0.0/0"},
+		},
+		{
+			name:   "IPv6 range",
+			within: "2001:db8::/32",
+			err:    "only IPv4 CIDRs are supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			free, err := FreeCIDRs(tt.within, tt.reserved, tt.prefixSize)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to contain %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(free, tt.free) {
+				t.Errorf("free %v, want %v", free, tt.free)
+			}
+		})
+	}
+}
+
+func TestRangeToCIDRs(t *testing.T) {
+	tests := []struct {
+		name  string
+		first string
+		last  string
+		cidrs []string
+	}{
+		{name: "aligned block", first: "10.0.0.0", last: "10.0.0.255", cidrs: []string{"10.0.0.0/24"}},
+		{name: "unaligned range", first: "10.0.0.1", last: "10.0.0.6", cidrs: []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{name: "single address", first: "255.255.255.255", last: "255.255.255.255", cidrs: []string{"255.255.255.255/32"}},
+		{name: "every address", first: "0.0.0.0", last: "255.255.255.255", cidrs: []string{"0.0.0.0/0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ipRange{
+				first: uint64(binary.BigEndian.Uint32(net.ParseIP(tt.first).To4())),
+				last:  uint64(binary.BigEndian.Uint32(net.ParseIP(tt.last).To4())),
+			}
+
+			var cidrs []string
+
+			for _, cidr := range rangeToCIDRs(r) {
+				cidrs = append(cidrs, cidr.String())
+			}
+
+			if !reflect.DeepEqual(cidrs, tt.cidrs) {
+				t.Errorf("cidrs %v, want %v", cidrs, tt.cidrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Package templates holds the built-in CloudFormation and Terraform templates, embedded in the
+// binary so the CLI works from any directory.
+package templates
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+// Names of the built-in templates.
+const (
+	DynamoDBTable = "cloudformation/dynamodb_table.yml"
+	IAMRole       = "cloudformation/iam_role.yml"
+	HubPolicy     = "cloudformation/hub_policy.yml"
+
+	TerraformDynamoDBTable = "terraform/dynamodb_table.tf"
+	TerraformIAMRole       = "terraform/iam_role.tf"
+)
+
+//go:embed cloudformation/*.yml terraform/*.tf
+var files embed.FS
+
+// Names returns the names of all built-in templates.
+func Names() ([]string, error) {
+	return fs.Glob(files, "*/*")
+}
+
+// Read returns the content of a built-in template.
+func Read(name string) ([]byte, error) {
+	content, err := files.ReadFile(name)
+
+	if err != nil {
+		return nil, fmt.Errorf("built-in template %s not found", name)
+	}
+
+	return content, nil
+}
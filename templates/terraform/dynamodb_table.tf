@@ -0,0 +1,61 @@
+# DynamoDB table for storing VPC CIDR blocks
+
+resource "aws_dynamodb_table" "vpc_cidr_table" {
+  name         = "{{.TableName}}"
+  billing_mode = "{{.BillingMode}}"
+  hash_key     = "CIDR"
+{{- if .Provisioned}}
+
+  read_capacity  = {{.ReadCapacity}}
+  write_capacity = {{.WriteCapacity}}
+{{- end}}
+
+  deletion_protection_enabled = {{.DeletionProtection}}
+
+  attribute {
+    name = "CIDR"
+    type = "S"
+  }
+
+  point_in_time_recovery {
+    enabled = {{.PointInTimeRecovery}}
+  }
+{{- if .Global}}
+
+  stream_enabled   = true
+  stream_view_type = "NEW_AND_OLD_IMAGES"
+{{- range .ReplicaRegions}}
+{{- if ne . $.Region}}
+
+  replica {
+    region_name            = "{{.}}"
+    point_in_time_recovery = {{$.PointInTimeRecovery}}
+    propagate_tags         = true
+  }
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .KMSKeyArn}}
+
+  server_side_encryption {
+    enabled     = true
+    kms_key_arn = "{{.KMSKeyArn}}"
+  }
+{{- end}}
+{{- if .Tags}}
+
+  tags = {
+{{- range $key, $value := .Tags}}
+    "{{$key}}" = "{{$value}}"
+{{- end}}
+  }
+{{- end}}
+}
+
+output "table_name" {
+  value = aws_dynamodb_table.vpc_cidr_table.name
+}
+
+output "table_arn" {
+  value = aws_dynamodb_table.vpc_cidr_table.arn
+}
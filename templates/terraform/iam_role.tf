@@ -0,0 +1,73 @@
+# IAM Role for VPC CIDR Manager
+
+data "aws_iam_policy_document" "vpc_cidr_manager_trust" {
+  statement {
+    effect  = "Allow"
+    actions = ["sts:AssumeRole"]
+
+    principals {
+      type        = "AWS"
+      identifiers = ["{{.Principal}}"]
+    }
+{{- if .ExternalID}}
+
+    condition {
+      test     = "StringEquals"
+      variable = "sts:ExternalId"
+      values   = ["{{.ExternalID}}"]
+    }
+{{- end}}
+{{- if .RequireMFA}}
+
+    condition {
+      test     = "Bool"
+      variable = "aws:MultiFactorAuthPresent"
+      values   = ["true"]
+    }
+{{- end}}
+  }
+}
+
+data "aws_partition" "current" {}
+
+data "aws_caller_identity" "current" {}
+
+data "aws_iam_policy_document" "vpc_cidr_manager" {
+  statement {
+    effect = "Allow"
+    actions = [
+      "ec2:DescribeVpcs",
+      "ec2:DescribeSubnets",
+      "ec2:DescribeTransitGatewayAttachments",
+      "ec2:SearchTransitGatewayRoutes",
+      "ec2:DescribeVpcPeeringConnections",
+    ]
+    resources = ["*"]
+  }
+
+  statement {
+    effect    = "Allow"
+    actions   = ["ec2:CreateTags"]
+    resources = ["arn:${data.aws_partition.current.partition}:ec2:*:${data.aws_caller_identity.current.account_id}:vpc/*"]
+  }
+}
+
+resource "aws_iam_role" "vpc_cidr_manager" {
+  name               = "{{.RoleName}}"
+  path               = "{{.RolePath}}"
+  assume_role_policy = data.aws_iam_policy_document.vpc_cidr_manager_trust.json
+{{- if .MaxSessionDuration}}
+
+  max_session_duration = {{.MaxSessionDuration}}
+{{- end}}
+}
+
+resource "aws_iam_role_policy" "vpc_cidr_manager" {
+  name   = "VPC-CIDR-Manager-Policy"
+  role   = aws_iam_role.vpc_cidr_manager.id
+  policy = data.aws_iam_policy_document.vpc_cidr_manager.json
+}
+
+output "role_arn" {
+  value = aws_iam_role.vpc_cidr_manager.arn
+}